@@ -4,12 +4,14 @@ go 1.23.0
 
 toolchain go1.24.1
 
-require github.com/gordonklaus/portaudio v0.0.0-20250206071425-98a94950218b
+require (
+	github.com/gordonklaus/portaudio v0.0.0-20250206071425-98a94950218b
+	github.com/schollz/progressbar/v3 v3.18.0
+)
 
 require (
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
-	github.com/schollz/progressbar/v3 v3.18.0 // indirect
 	golang.org/x/sys v0.29.0 // indirect
 	golang.org/x/term v0.28.0 // indirect
 )
@@ -6,8 +6,10 @@ import (
 )
 
 // テキストの要約生成
-func GenerateSummary(text string) (string, error) {
+// hintsは録音中にブックマークされた箇所で、要約で重視させる
+func GenerateSummary(text string, hints []string) (string, error) {
 	systemPrompt := "あなたは優秀な要約者です。与えられたテキストを30字程度で要約してください。"
+	systemPrompt += bookmarkHint(hints)
 	// 直接インポートすると循環参照になるため、app.QueryOllamaを直接使用せず
 	// 代わりにqueryOllamaヘルパー関数を使う
 	summary, err := queryOllama(text, systemPrompt)
@@ -20,8 +22,9 @@ func GenerateSummary(text string) (string, error) {
 }
 
 // キーワード抽出
-func ExtractKeywords(text string) ([]string, error) {
+func ExtractKeywords(text string, hints []string) ([]string, error) {
 	systemPrompt := "次の文から最も重要なキーワードを3〜5つ抽出し、カンマ区切りのリストで返してください。"
+	systemPrompt += bookmarkHint(hints)
 	keywordsText, err := queryOllama(text, systemPrompt)
 	if err != nil {
 		return nil, err
@@ -37,8 +40,9 @@ func ExtractKeywords(text string) ([]string, error) {
 }
 
 // 問題点抽出
-func IdentifyIssues(text string) (string, error) {
+func IdentifyIssues(text string, hints []string) (string, error) {
 	systemPrompt := "次の文から言及されている問題点や課題を短く抽出してください。問題が見つからない場合は「特に問題点はありません」と返してください。"
+	systemPrompt += bookmarkHint(hints)
 	issues, err := queryOllama(text, systemPrompt)
 	if err != nil {
 		return "", err
@@ -73,3 +77,12 @@ func CheckAggressiveLanguage(text string) (string, error) {
 	fmt.Printf("  攻撃的言葉チェック完了: %s\n", aggressiveCheck)
 	return aggressiveCheck, nil
 }
+
+// ブックマークされた箇所をシステムプロンプトに追加するヒント
+func bookmarkHint(hints []string) string {
+	if len(hints) == 0 {
+		return ""
+	}
+	return "\n\n録音中に参加者が次の箇所を重要としてブックマークしました。これらの内容を特に重視してください。\n" +
+		strings.Join(hints, "\n")
+}
@@ -9,10 +9,10 @@ import (
 	"time"
 )
 
-const (
-	OllamaAPIURL = "http://localhost:11434/api"
-	OllamaModel  = "gemma3:4b"
-)
+const OllamaModel = "gemma3:4b"
+
+// OllamaのAPIのURL（テストではスタブのサーバーに差し替える）
+var OllamaAPIURL = "http://localhost:11434/api"
 
 // Ollama API用の構造体
 type ollamaRequest struct {
@@ -3,14 +3,14 @@ package app
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/gordonklaus/portaudio"
-
 	"whisper_local_faster_whsiper_go/internal/audio"
 )
 
@@ -20,26 +20,81 @@ const (
 	RecordingSeconds = 30    // 録音間隔（秒）
 	OllamaAPIURL     = "http://localhost:11434/api"
 	OllamaModel      = "gemma3:4b"
+
+	StreamFailureTimeout = 3 * time.Second // 読み取り失敗がこの時間続いたらデバイスを開き直す
+)
+
+// 無音セグメントの扱い
+const (
+	SilenceActionDrop = "drop" // 破棄する
+	SilenceActionNote = "note" // 破棄してマークダウンに無音と記録する
+)
+
+// 複数チャンネルの扱い
+const (
+	ChannelModeMix         = "mix"         // モノラルに混合して1ファイルに保存
+	ChannelModeInterleaved = "interleaved" // 複数チャンネルのWAVとして保存
+	ChannelModeSplit       = "split"       // チャンネルごとに別ファイルで保存
 )
 
 // Appはアプリケーション全体を管理する構造体
 type App struct {
-	AudioBuffer      [][]float32        // 音声バッファ
-	LastSaveTime     time.Time          // 最後の保存時刻
-	IsRecording      bool               // 録音中フラグ
-	RecordingDir     string             // 録音保存ディレクトリ
-	TranscriptsDir   string             // 文字起こし保存ディレクトリ
-	TranscribeScript string             // 文字起こしスクリプト
-	AllTranscripts   []string           // すべての文字起こし
-	PendingFiles     []string           // 処理待ちファイル
-	MdFile           string             // マークダウンファイル
-	DeviceName       string             // デバイス名
-	SampleRate       int                // サンプリングレート
-	RecordInterval   float64            // 録音間隔（秒）
-	Mutex            sync.Mutex         // ミューテックス
-	WG               sync.WaitGroup     // WaitGroup
-	ProcessAudioFunc func(*App, string) // 音声処理関数
-	animationStopCh  chan struct{}      // アニメーション停止用チャネル
+	AudioBuffer      [][]float32            // 音声バッファ
+	LastSaveTime     time.Time              // 最後の保存時刻
+	IsRecording      bool                   // 録音中フラグ
+	RecordingDir     string                 // 録音保存ディレクトリ
+	TranscriptsDir   string                 // 文字起こし保存ディレクトリ
+	TranscribeScript string                 // 文字起こしスクリプト
+	TranscriberName  string                 // 文字起こしのバックエンド名
+	AllTranscripts   []string               // すべての文字起こし
+	PendingFiles     []Segment              // 処理待ちセグメント
+	MdFile           string                 // マークダウンファイル
+	DeviceName       string                 // デバイス名
+	SampleRate       int                    // サンプリングレート
+	Channels         int                    // 入力チャンネル数
+	ChannelMode      string                 // 複数チャンネルの扱い（mix/interleaved/split）
+	ChannelNames     []string               // チャンネルごとの話者名
+	RecordInterval   float64                // 録音間隔（秒）
+	UseVAD           bool                   // 発話の切れ目で区切るか
+	WhisperReady     bool                   // whisper用に16kHzへ変換して保存するか
+	KeepOriginal     bool                   // 元のサンプリングレートの音声も保管するか
+	ArchiveFlac      bool                   // 文字起こし後にWAVをFLACに変換して保管するか
+	WavOptions       audio.WavOptions       // 保存するWAVのサンプル形式とディザー
+	SilenceThreshold float64                // 無音判定のRMS閾値（0で無効）
+	SilenceMinActive float64                // 発話フレームの割合がこれ未満なら無音とみなす
+	SilenceAction    string                 // 無音セグメントの扱い（drop/note）
+	SilentSegments   int                    // 無音でスキップしたセグメント数
+	VAD              audio.VADConfig        // 発話区間検出の設定
+	Mutex            sync.Mutex             // ミューテックス
+	saveMutex        sync.Mutex             // セグメントの保存を直列化するミューテックス
+	WG               sync.WaitGroup         // WaitGroup
+	ProcessAudioFunc func(*App, Segment)    // 音声処理関数
+	Meter            *LevelMeter            // 入力レベルメーター
+	TotalDropped     int64                  // 欠落フレーム数の合計
+	TotalClipped     int64                  // 保存時に飽和させたサンプル数の合計
+	bufferDropped    int64                  // AudioBufferの録音中に欠落したフレーム数
+	journal          *audio.StreamWavWriter // 録音中の音声のジャーナル
+	journalPath      string                 // ジャーナルファイルのパス
+	animationStopCh  chan struct{}          // アニメーション停止用チャネル
+	animating        bool                   // アニメーション動作中フラグ
+	paused           atomic.Bool            // 一時停止中フラグ
+	PauseCount       int                    // 一時停止した回数
+	FallbackDevices  []string               // 入力デバイスが失われた場合の代替デバイス名
+	DeviceFailures   int                    // 入力デバイスが失われた回数
+	SessionID        string                 // 録音セッションのID
+	segmentSeq       int                    // 最後に作成したセグメントの通し番号
+	timelineFrames   int64                  // セッション開始からの経過フレーム数（保存済みのセグメントの終端）
+	capturedFrames   int64                  // セッション開始からの経過フレーム数（録音中のデータを含む）
+	Bookmarks        []Bookmark             // 録音中に記録したブックマーク
+	BookmarkHints    []string               // 分析のヒントにするブックマーク付きの文字起こし
+	pendingBookmarks []Bookmark             // まだセグメントに割り当てていないブックマーク
+	Retention        RetentionPolicy        // 録音と文字起こし結果の保存ルール
+	transcribed      map[string]bool        // 文字起こし済みのセグメント名
+	sessionNotes     map[string][]string    // セグメントの後に記録するメモ
+	lastQueued       string                 // 最後に処理待ちに追加したファイル
+	inFlight         string                 // 処理中のファイル
+	overview         *audio.Overview        // セッション全体の波形とスペクトログラム
+	overviewMutex    sync.Mutex             // overviewのミューテックス
 }
 
 // 新しいアプリケーションインスタンスを作成
@@ -62,23 +117,37 @@ func NewApp() *App {
 	os.MkdirAll(recordingsDir, 0755)
 	os.MkdirAll(transcriptsDir, 0755)
 
-	timestamp := time.Now().Format("20060102_1504")
+	now := time.Now()
+	timestamp := now.Format("20060102_1504")
 	mdFile := filepath.Join(transcriptsDir, fmt.Sprintf("%s_all_communication.md", timestamp))
 
 	return &App{
 		AudioBuffer:      make([][]float32, 0),
-		LastSaveTime:     time.Now(),
+		LastSaveTime:     now,
 		IsRecording:      true,
 		RecordingDir:     recordingsDir,
 		TranscriptsDir:   transcriptsDir,
 		TranscribeScript: transcribeScript,
 		AllTranscripts:   make([]string, 0),
-		PendingFiles:     make([]string, 0),
+		PendingFiles:     make([]Segment, 0),
 		MdFile:           mdFile,
 		SampleRate:       SampleRate,
+		Channels:         1,
+		ChannelMode:      ChannelModeMix,
 		RecordInterval:   RecordingSeconds,
+		UseVAD:           true,
+		WhisperReady:     true,
+		KeepOriginal:     false,
+		SilenceThreshold: 0.01,
+		SilenceMinActive: 0.02,
+		SilenceAction:    SilenceActionNote,
+		VAD:              audio.DefaultVADConfig(),
 		ProcessAudioFunc: nil, // 後で設定
+		Meter:            NewLevelMeter(),
 		animationStopCh:  make(chan struct{}),
+		SessionID:        now.Format("20060102_150405"),
+		transcribed:      make(map[string]bool),
+		sessionNotes:     make(map[string][]string),
 	}
 }
 
@@ -102,256 +171,559 @@ func (app *App) PrintSystemInfo() {
 	fmt.Printf("%s- 録音ディレクトリ:%s %s\n", Bold, Reset, app.RecordingDir)
 	fmt.Printf("%s- 文字起こしディレクトリ:%s %s\n", Bold, Reset, app.TranscriptsDir)
 	fmt.Printf("%s- 文字起こしスクリプト:%s %s\n", Bold, Reset, app.TranscribeScript)
+	if app.TranscriberName != "" {
+		fmt.Printf("%s- 文字起こしのバックエンド:%s %s\n", Bold, Reset, app.TranscriberName)
+	}
 }
 
 // オーディオデータを保存
+// バッファの受け取りとセグメントの割り当てだけをロック中に行い、
+// リサンプリングとファイルへの書き込みはロックを外して行う
 func (app *App) SaveAudioSegment() {
-	app.Mutex.Lock()
-	defer app.Mutex.Unlock()
+	// 保存は直列に行い、処理待ちリストの順序を通し番号の順に保つ
+	app.saveMutex.Lock()
+	defer app.saveMutex.Unlock()
 
+	app.Mutex.Lock()
 	if len(app.AudioBuffer) == 0 {
+		app.Mutex.Unlock()
 		return
 	}
 
+	// 欠落フレームの合計（無音セグメントも含む）
+	app.TotalDropped += app.bufferDropped
+
 	// バッファの詳細情報を集計
+	channels := app.bufferChannels()
 	totalSamples := 0
 	for _, buffer := range app.AudioBuffer {
 		totalSamples += len(buffer)
 	}
+	totalFrames := totalSamples / channels
+	duration := float64(totalFrames) / float64(app.SampleRate)
+
+	// 無音のセグメントはwhisperとOllamaに渡さない
+	if app.isSilentSegment() {
+		app.SilentSegments++
+		start := framesToDuration(app.timelineFrames, app.SampleRate)
+		app.timelineFrames += int64(totalFrames) + app.bufferDropped
+		bookmarks := app.takeBookmarksLocked(app.timelineFrames)
+		fmt.Printf("\n%s\n", InfoMessage(fmt.Sprintf("無音のためスキップ (%.1f秒)", duration)))
+		// ブックマークがある場合は破棄する設定でも記録を残す
+		if app.SilenceAction == SilenceActionNote || len(bookmarks) > 0 {
+			note := silenceNote(start, framesToDuration(app.timelineFrames, app.SampleRate), duration, bookmarks)
+			if !app.deferSessionNoteLocked(note) {
+				app.writeSessionNote(note)
+			}
+		}
+		app.AudioBuffer = make([][]float32, 0)
+		app.bufferDropped = 0
+		app.LastSaveTime = time.Now()
+		app.Mutex.Unlock()
+		return
+	}
+
+	// セッション内の位置（欠落したフレームも時間軸に含める）
+	segmentInfo := app.nextSegmentLocked(int64(totalFrames) + app.bufferDropped)
+	segmentInfo.Dropped = app.bufferDropped
+	segmentInfo.Bookmarks = app.takeBookmarksLocked(segmentInfo.End)
+
+	// WAVに埋め込むセッションのメタデータ（録音開始時刻はセグメントの長さから逆算）
+	options := app.WavOptions
+	options.Metadata = &audio.WavMetadata{
+		SessionID:   segmentInfo.SessionID,
+		Sequence:    segmentInfo.Sequence,
+		Offset:      segmentInfo.StartOffset(),
+		Device:      app.DeviceName,
+		CaptureTime: time.Now().Add(-framesToDuration(segmentInfo.End-segmentInfo.Start, app.SampleRate)),
+	}
+	for _, bookmark := range segmentInfo.Bookmarks {
+		options.Metadata.Bookmarks = append(options.Metadata.Bookmarks, audio.Marker{Offset: bookmark.Offset, Label: bookmark.Label})
+	}
+
+	// バッファを受け取ってクリアし、録音を続けられるようにする
+	buffer := app.AudioBuffer
+	sampleRate := app.SampleRate
+	keepOriginal := app.KeepOriginal
+	whisperReady := app.WhisperReady
+	split := app.ChannelMode == ChannelModeSplit && channels > 1
+	recordingDir := app.RecordingDir
+	app.AudioBuffer = make([][]float32, 0)
+	app.bufferDropped = 0
+	app.LastSaveTime = time.Now()
+	app.Mutex.Unlock()
 
 	// ファイル名と保存先の設定
-	timestamp := time.Now().Format("20060102_150405")
-	filename := fmt.Sprintf("recording_%s.wav", timestamp)
-	filepath := filepath.Join(app.RecordingDir, filename)
+	filename := fmt.Sprintf("recording_%s_%04d.wav", segmentInfo.SessionID, segmentInfo.Sequence)
+	archiveDir := filepath.Join(recordingDir, "original")
+	archivePath := filepath.Join(archiveDir, filename)
+	filepath := filepath.Join(recordingDir, filename)
+
+	// 元のサンプリングレートのまま保管する場合
+	if keepOriginal {
+		os.MkdirAll(archiveDir, 0755)
+		if _, err := audio.WriteWavFile(archivePath, buffer, sampleRate, channels, options); err != nil {
+			fmt.Printf("%s\n", ErrorMessage("元音声の保存エラー: "+err.Error()))
+		}
+	}
+
+	// whisper用に16kHzへ変換
+	segment := buffer
+	segmentRate := sampleRate
+	if whisperReady && sampleRate != audio.WhisperSampleRate {
+		perChannel := audio.Deinterleave(audio.Concat(buffer), channels)
+		for c := range perChannel {
+			perChannel[c] = audio.Resample(perChannel[c], sampleRate, audio.WhisperSampleRate)
+		}
+		segment = [][]float32{audio.Interleave(perChannel)}
+		segmentRate = audio.WhisperSampleRate
+	}
 
 	// WAV形式でオーディオデータを保存
-	err := audio.SaveAsWav(filepath, app.AudioBuffer, app.SampleRate)
+	savedFiles := []string{filepath}
+	var clipped int64
+	var err error
+	if split {
+		// チャンネルごとに保存し、セグメントはチャンネル別ファイルの組として扱う
+		savedFiles, clipped, err = audio.SaveAsWavPerChannel(filepath, segment, segmentRate, channels, options)
+	} else {
+		clipped, err = audio.WriteWavFile(filepath, segment, segmentRate, channels, options)
+	}
 	if err != nil {
 		fmt.Printf("%s\n", ErrorMessage("録音ファイル保存エラー: "+err.Error()))
 		return
 	}
 
 	// ファイル存在確認
-	if _, err := os.Stat(filepath); os.IsNotExist(err) {
-		fmt.Printf("%s\n", ErrorMessage("録音ファイルが作成されませんでした: "+filepath))
-	} else {
-		duration := float64(totalSamples) / float64(app.SampleRate)
-		fmt.Printf("\n%s\n", SuccessMessage(fmt.Sprintf("録音保存: %s (%.1f秒)", filepath, duration)))
+	for _, saved := range savedFiles {
+		if _, err := os.Stat(saved); os.IsNotExist(err) {
+			fmt.Printf("%s\n", ErrorMessage("録音ファイルが作成されませんでした: "+saved))
+		} else {
+			fmt.Printf("\n%s\n", SuccessMessage(fmt.Sprintf("録音保存: %s (%s)", saved, segmentInfo.Label())))
+		}
+	}
+
+	// 欠落したフレームを記録
+	if segmentInfo.Dropped > 0 {
+		fmt.Printf("%s\n", WarningMessage(fmt.Sprintf("このセグメントで %d フレーム（%.2f秒）の音声が欠落しました",
+			segmentInfo.Dropped, float64(segmentInfo.Dropped)/float64(sampleRate))))
+	}
+
+	// 範囲外のサンプルを飽和させた数を記録
+	if clipped > 0 {
+		fmt.Printf("%s\n", WarningMessage(fmt.Sprintf("このセグメントで %d サンプルがクリップしました（入力レベルが高すぎます）", clipped)))
 	}
+	segmentInfo.Clipped = clipped
 
 	// 処理待ちリストに追加
-	app.PendingFiles = append(app.PendingFiles, filepath)
+	segmentInfo.Path = filepath
+	app.Mutex.Lock()
+	app.TotalClipped += clipped
+	app.queueSegmentLocked(segmentInfo)
+	app.Mutex.Unlock()
+}
 
-	// バッファをクリアして時間をリセット
-	app.AudioBuffer = make([][]float32, 0)
-	app.LastSaveTime = time.Now()
+// バッファ中の音声が無音かどうかを判定（呼び出し側でロックを保持すること）
+func (app *App) isSilentSegment() bool {
+	if app.SilenceThreshold <= 0 {
+		return false
+	}
+	samples := audio.Concat(app.AudioBuffer)
+	if channels := app.bufferChannels(); channels > 1 {
+		samples = appendMono(nil, samples, channels)
+	}
+	return audio.ActiveRatio(samples, app.SampleRate, app.SilenceThreshold) < app.SilenceMinActive
 }
 
-// 録音処理のメインループ - カッコいい表示付き
-func (app *App) StartRecording(ctx context.Context, device *portaudio.DeviceInfo) error {
+// 任意の入力源から録音し、発話の切れ目または一定間隔ごとにセグメントを保存する
+// 入力源がio.EOFを返した場合は残りを保存して終了する
+func (app *App) RecordFromSource(ctx context.Context, source audio.AudioSource) error {
+	if err := source.Open(); err != nil {
+		return err
+	}
+	defer source.Close()
+
+	// 入力源の形式に合わせる
+	app.SampleRate = source.SampleRate()
+	app.Channels = source.Channels()
+
 	fmt.Println(SectionHeader("録音開始"))
-	fmt.Printf("%s選択デバイス:%s %s%s%s\n", Bold, Reset, Green, device.Name, Reset)
+	fmt.Printf("%s選択デバイス:%s %s%s%s\n", Bold, Reset, Green, app.DeviceName, Reset)
 	fmt.Printf("%sサンプルレート:%s %s%d Hz%s\n", Bold, Reset, Yellow, app.SampleRate, Reset)
-	fmt.Printf("%s録音間隔:%s %s%.1f秒%s\n", Bold, Reset, Yellow, app.RecordInterval, Reset)
+	fmt.Printf("%sチャンネル数:%s %s%d%s\n", Bold, Reset, Yellow, app.Channels, Reset)
+	if app.bufferChannels() > 1 {
+		labels := make([]string, app.Channels)
+		for c := range labels {
+			labels[c] = app.ChannelLabel(c)
+		}
+		fmt.Printf("%sチャンネル別文字起こし:%s %s%s%s\n", Bold, Reset, Yellow, strings.Join(labels, ", "), Reset)
+	}
+	if app.UseVAD {
+		fmt.Printf("%s区切り:%s %s発話の切れ目 (%.1f〜%.1f秒)%s\n", Bold, Reset, Yellow,
+			app.VAD.MinSegment.Seconds(), app.VAD.MaxSegment.Seconds(), Reset)
+	} else {
+		fmt.Printf("%s録音間隔:%s %s%.1f秒%s\n", Bold, Reset, Yellow, app.RecordInterval, Reset)
+	}
 	fmt.Printf("%s出力ファイル:%s %s\n", Bold, Reset, app.MdFile)
 	fmt.Println(InfoMessage("Ctrl+C で録音を停止します"))
+	fmt.Println(InfoMessage("p + Enter（または SIGUSR1 / SIGUSR2）で一時停止・再開します"))
+	fmt.Println(InfoMessage("b + Enter でブックマークを記録します（例: b 予算の結論）"))
 
-	// バッファサイズを調整（大きめに設定）
 	framesPerBuffer := app.SampleRate / 4 // 0.25秒分
-	// 録音間隔の4倍のバッファ数 - 未使用のためコメントアウト
-	// numBuffers := int(app.RecordInterval * 4)
+	segmentSamples := int(float64(app.SampleRate) * app.RecordInterval)
 
-	// 録音データを格納するスライス
-	recordedData := make([]float32, 0, app.SampleRate*int(app.RecordInterval))
+	// 録音データを格納するスライス（複数チャンネル時はインターリーブ）
+	bufCh := app.bufferChannels()
+	recordedData := make([]float32, 0, segmentSamples*bufCh)
 
-	// 実際のストリームバッファ（一時的な録音用）
-	buffer := make([]float32, framesPerBuffer)
+	// 読み取り用バッファ（チャンネルはインターリーブ）
+	buffer := make([]float32, framesPerBuffer*app.Channels)
 
-	// デフォルトの入力ストリームを開く
-	stream, err := portaudio.OpenDefaultStream(1, 0, float64(app.SampleRate), framesPerBuffer, buffer)
-	if err != nil {
-		return fmt.Errorf("ストリームを開けませんでした: %v", err)
+	// 発話区間検出による区切り
+	var segmenter *audio.Segmenter
+	if app.UseVAD {
+		segmenter = audio.NewSegmenter(app.VAD, app.SampleRate)
+		segmentSamples = int(app.VAD.MaxSegment.Seconds() * float64(app.SampleRate))
 	}
-	defer stream.Close()
 
-	// ストリームを開始
-	err = stream.Start()
-	if err != nil {
-		return fmt.Errorf("ストリームを開始できませんでした: %v", err)
-	}
-	defer stream.Stop()
+	// 強制終了に備えて録音中の音声を逐次ファイルに書き込む
+	app.openJournal()
+	defer app.closeJournal()
 
 	// 録音中アニメーションを開始
-	go RecordingAnimation(app.animationStopCh)
+	app.Meter.Reset()
+	app.startAnimation()
 
-	// タイマーを設定
-	ticker := time.NewTicker(250 * time.Millisecond) // 0.25秒ごとに読み取り
-	defer ticker.Stop()
+	// 欠落フレームの集計（入力源が報告できる場合のみ）
+	dropReporter, _ := source.(audio.DropReporter)
+	var lastDropped, segmentDropped int64
 
-	// 録音の開始時間
-	startTime := time.Now()
+	// 一時停止の状態（録音ループ側で切り替えを検出する）
+	paused := false
 
-	// オーバーフローカウンター
-	overflowCount := 0
-	maxOverflows := 10 // 最大許容オーバーフロー回数
+	// 読み取りの失敗が始まった時刻
+	var failingSince time.Time
 
 	// 録音ループ
 	for {
 		select {
 		case <-ctx.Done():
 			// アニメーションを停止
-			close(app.animationStopCh)
-
-			if len(recordedData) > 0 {
-				// 残りのデータを保存
-				app.Mutex.Lock()
-				app.AudioBuffer = append(app.AudioBuffer, recordedData)
-				app.Mutex.Unlock()
-				app.SaveAudioSegment()
-			}
+			app.stopAnimation()
+			app.flushRecordedData(recordedData, segmentDropped)
+			return nil
+		default:
+		}
 
+		// データを読み取り（入力源側でブロックする）
+		frames, err := source.Read(buffer)
+		if err == io.EOF {
+			// 入力源の終端に到達
+			app.stopAnimation()
+			app.flushRecordedData(recordedData, segmentDropped)
 			return nil
+		}
 
-		case <-ticker.C:
-			// データを読み取り
-			err = stream.Read()
-
-			if err != nil {
-				// エラーが発生した場合
-				if strings.Contains(err.Error(), "Input overflowed") {
-					overflowCount++
-					if overflowCount >= maxOverflows {
-						// バッファをリセットして続行
-						overflowCount = 0
-						continue
-					}
-				} else {
-					// アニメーションを一時的に停止して通常表示に戻す
-					close(app.animationStopCh)
-					app.animationStopCh = make(chan struct{})
-					fmt.Printf("\r%s\n", ErrorMessage("読み取りエラー: "+err.Error()))
-					go RecordingAnimation(app.animationStopCh) // アニメーション再開
+		if err != nil {
+			// 失敗が続いている間は最初のエラーだけを表示
+			if failingSince.IsZero() {
+				failingSince = time.Now()
+				wasAnimating := app.stopAnimation()
+				fmt.Printf("\r%s\n", ErrorMessage("読み取りエラー: "+err.Error()))
+				if wasAnimating {
+					app.startAnimation() // アニメーション再開
 				}
+			}
+
+			// 一定時間失敗が続いた場合はデバイスが失われたとみなして開き直す
+			recoverable, ok := source.(audio.Recoverable)
+			if !ok || time.Since(failingSince) < StreamFailureTimeout {
 				continue
 			}
 
-			// バッファからデータをコピー
-			bufferCopy := make([]float32, len(buffer))
-			copy(bufferCopy, buffer)
-
-			// 録音データに追加
-			recordedData = append(recordedData, bufferCopy...)
-
-			// 経過時間をチェック
-			elapsed := time.Since(startTime).Seconds()
-
-			if elapsed >= app.RecordInterval {
-				// アニメーションを一時的に停止して通常表示に戻す
-				close(app.animationStopCh)
-				app.animationStopCh = make(chan struct{})
-
-				// データをアプリケーションバッファに追加
-				app.Mutex.Lock()
-				// 複数のチャンクに分割して追加（より効率的な処理のため）
-				chunkSize := app.SampleRate // 1秒ごとのチャンク
-				numChunks := len(recordedData) / chunkSize
-
-				for i := 0; i < numChunks; i++ {
-					start := i * chunkSize
-					end := start + chunkSize
-					if end > len(recordedData) {
-						end = len(recordedData)
-					}
-
-					chunk := make([]float32, end-start)
-					copy(chunk, recordedData[start:end])
-					app.AudioBuffer = append(app.AudioBuffer, chunk)
-				}
+			app.stopAnimation()
+			app.flushRecordedData(recordedData, segmentDropped)
+			recordedData = make([]float32, 0, segmentSamples*bufCh)
+			segmentDropped = 0
+			app.Meter.ResetDropped()
+			if segmenter != nil {
+				segmenter.Reset()
+			}
+			app.closeJournal()
 
-				// 残りのデータがあれば追加
-				if len(recordedData)%chunkSize > 0 {
-					start := numChunks * chunkSize
-					chunk := make([]float32, len(recordedData)-start)
-					copy(chunk, recordedData[start:])
-					app.AudioBuffer = append(app.AudioBuffer, chunk)
-				}
+			lostAt := time.Now()
+			deviceName := recoverable.Name()
+			app.Mutex.Lock()
+			app.DeviceFailures++
+			app.Mutex.Unlock()
+			app.AddSessionNote(deviceNote(fmt.Sprintf("⚠ 入力デバイスが失われました（%s）", deviceName), app.SessionOffset()))
+			fmt.Printf("\r%s\n", WarningMessage(fmt.Sprintf("入力デバイス %s が応答しません。再接続を待っています...", deviceName)))
+
+			if err := recoverable.Recover(ctx); err != nil {
+				// 待機中に録音が停止された
+				return nil
+			}
+
+			// 読み取りに失敗し始めてから復帰までをセッションの時間軸に含める
+			gap := time.Since(lostAt)
+			app.skipTimeline(int64(time.Since(failingSince).Seconds() * float64(app.SampleRate)))
+			if name := recoverable.Name(); name != deviceName {
+				app.DeviceName = name
+			}
+			app.AddSessionNote(deviceNote(fmt.Sprintf("✓ 入力デバイスが復帰しました（%s、中断 %.0f 秒）", recoverable.Name(), gap.Seconds()), app.SessionOffset()))
+			fmt.Printf("\r%s\n", SuccessMessage(fmt.Sprintf("入力デバイス %s で録音を再開しました（中断 %.0f秒）", recoverable.Name(), gap.Seconds())))
+
+			failingSince = time.Time{}
+			app.openJournal()
+			app.Meter.Reset()
+			if !paused {
+				app.startAnimation()
+			}
+			continue
+		}
+		failingSince = time.Time{}
+
+		// 欠落したフレームを現在のセグメントに計上
+		if dropReporter != nil {
+			if total := dropReporter.DroppedFrames(); total > lastDropped {
+				segmentDropped += total - lastDropped
+				app.Meter.AddDropped(total - lastDropped)
+				app.advanceCaptured(total - lastDropped)
+				lastDropped = total
+			}
+		}
 
-				app.Mutex.Unlock()
+		// 一時停止中は入力を読み捨てる（デバイスのバッファを溢れさせないため読み取りは続ける）
+		if app.IsPaused() {
+			if !paused {
+				paused = true
+				app.stopAnimation()
+
+				// 現在のセグメントを区切って保存
+				app.flushRecordedData(recordedData, segmentDropped)
+				recordedData = make([]float32, 0, segmentSamples*bufCh)
+				segmentDropped = 0
+				app.Meter.ResetDropped()
+				if segmenter != nil {
+					segmenter.Reset()
+				}
+				app.closeJournal()
 
-				// セグメントを保存
-				app.SaveAudioSegment()
+				app.AddSessionNote(pauseNote("⏸ 一時停止", app.SessionOffset()))
+				fmt.Printf("\r%s\n", InfoMessage("録音を一時停止しました（p + Enter または SIGUSR2 で再開）"))
+			}
+			// 読み捨てた分もセッションの時間軸に含める
+			app.skipTimeline(int64(frames))
+			continue
+		}
+		if paused {
+			paused = false
+			app.skipTimeline(int64(frames))
+			app.openJournal()
+			app.Meter.Reset()
+
+			app.AddSessionNote(pauseNote("▶ 再開", app.SessionOffset()))
+			fmt.Printf("\r%s\n", InfoMessage("録音を再開しました"))
+			app.startAnimation()
+			// 一時停止中に読み取ったデータは破棄し、次の読み取りから録音する
+			continue
+		}
 
-				// 録音データをリセット
-				recordedData = make([]float32, 0, app.SampleRate*int(app.RecordInterval))
+		if frames == 0 {
+			continue
+		}
 
-				// 開始時間をリセット
-				startTime = time.Now()
+		// 入力レベルを計測して表示に渡す
+		input := buffer[:frames*app.Channels]
+		app.Meter.Update(audio.RMS(input), audio.Peak(input))
 
-				// アニメーション再開
-				go RecordingAnimation(app.animationStopCh)
+		// バッファからデータを録音データに追加（混合モードではモノラルに変換）
+		added := len(recordedData)
+		if bufCh == 1 {
+			recordedData = appendMono(recordedData, input, app.Channels)
+		} else {
+			recordedData = append(recordedData, input...)
+		}
+		app.writeJournal(recordedData[added:])
+		app.advanceCaptured(int64(frames))
+
+		// 区切り位置を判定（VADは発話の切れ目、固定モードは録音間隔ごと）
+		var cuts []int
+		if segmenter != nil {
+			// 発話判定は全チャンネルを混合した信号で行う
+			for _, cut := range segmenter.Push(appendMono(nil, recordedData[added:], bufCh)) {
+				cuts = append(cuts, cut*bufCh)
 			}
+		} else if len(recordedData) >= segmentSamples*bufCh {
+			cuts = []int{len(recordedData)}
+		}
+
+		for _, cut := range cuts {
+			// アニメーションを一時的に停止して通常表示に戻す
+			app.stopAnimation()
+
+			// セグメントを保存
+			app.flushRecordedData(recordedData[:cut], segmentDropped)
+			segmentDropped = 0
+			app.Meter.ResetDropped()
+
+			// 区切り以降のデータを次のセグメントに持ち越す
+			rest := make([]float32, len(recordedData)-cut, segmentSamples*bufCh)
+			copy(rest, recordedData[cut:])
+			recordedData = rest
+
+			// 保存済みの部分のジャーナルを破棄し、持ち越し分から書き直す
+			app.closeJournal()
+			app.openJournal()
+			app.writeJournal(recordedData)
+
+			// アニメーション再開
+			app.startAnimation()
 		}
 	}
 }
 
-// 利用可能なオーディオデバイスを表示
-func (app *App) ListAudioDevices() ([]portaudio.DeviceInfo, error) {
-	devices, err := portaudio.Devices()
-	if err != nil {
-		return nil, fmt.Errorf("デバイス一覧の取得エラー: %v", err)
+// 録音中アニメーションを開始
+func (app *App) startAnimation() {
+	if app.animating {
+		return
 	}
+	app.animationStopCh = make(chan struct{})
+	app.animating = true
+	go RecordingAnimation(app.animationStopCh, app.Meter)
+}
 
-	fmt.Println(SectionHeader("利用可能なオーディオデバイス"))
-	inputDevices := make([]portaudio.DeviceInfo, 0)
+// 録音中アニメーションを停止（動作中だった場合はtrueを返す）
+func (app *App) stopAnimation() bool {
+	if !app.animating {
+		return false
+	}
+	close(app.animationStopCh)
+	app.animating = false
+	return true
+}
 
-	for i, device := range devices {
-		if device.MaxInputChannels > 0 {
-			fmt.Printf("  %s%d:%s %s%s%s (入力: %d)\n",
-				Bold+Green, i, Reset,
-				Cyan, device.Name, Reset,
-				device.MaxInputChannels)
-			inputDevices = append(inputDevices, *device)
+// 録音データをチャンクに分けてアプリケーションバッファに移し、セグメントとして保存
+// droppedはこのセグメントの録音中に欠落したフレーム数
+func (app *App) flushRecordedData(recordedData []float32, dropped int64) {
+	if len(recordedData) == 0 {
+		return
+	}
+
+	app.Mutex.Lock()
+	app.bufferDropped += dropped
+	// 複数のチャンクに分割して追加（より効率的な処理のため）
+	chunkSize := app.SampleRate * app.bufferChannels() // 1秒ごとのチャンク
+	for start := 0; start < len(recordedData); start += chunkSize {
+		end := start + chunkSize
+		if end > len(recordedData) {
+			end = len(recordedData)
+		}
+
+		chunk := make([]float32, end-start)
+		copy(chunk, recordedData[start:end])
+		app.AudioBuffer = append(app.AudioBuffer, chunk)
+	}
+	app.Mutex.Unlock()
+
+	app.SaveAudioSegment()
+}
+
+// 録音に使用するデバイスを設定
+// デバイスのデフォルトのサンプリングレートを採用し、
+// channelsが0の場合はデバイスの最大チャンネル数で録音する
+func (app *App) SelectDevice(device audio.DeviceInfo, channels int) error {
+	if device.Name == "" {
+		return fmt.Errorf("録音デバイスが指定されていません")
+	}
+	if device.MaxInputChannels < 1 {
+		return fmt.Errorf("デバイス %s は入力に対応していません", device.Name)
+	}
+
+	sampleRate := int(device.DefaultSampleRate)
+	if sampleRate <= 0 {
+		return fmt.Errorf("デバイス %s のサンプリングレートを取得できませんでした", device.Name)
+	}
+
+	if channels <= 0 {
+		channels = device.MaxInputChannels
+	}
+	if channels > device.MaxInputChannels {
+		return fmt.Errorf("デバイス %s は最大 %d チャンネルまでしか録音できません（要求: %d）",
+			device.Name, device.MaxInputChannels, channels)
+	}
+
+	app.DeviceName = device.Name
+	app.SampleRate = sampleRate
+	app.Channels = channels
+	return nil
+}
+
+// 録音バッファのチャンネル数（混合モードではモノラル）
+func (app *App) bufferChannels() int {
+	if app.ChannelMode == ChannelModeMix || app.Channels < 1 {
+		return 1
+	}
+	return app.Channels
+}
+
+// チャンネルの表示名（話者名が未設定の場合はチャンネル番号）
+func (app *App) ChannelLabel(ch int) string {
+	if ch < len(app.ChannelNames) && app.ChannelNames[ch] != "" {
+		return app.ChannelNames[ch]
+	}
+	return fmt.Sprintf("チャンネル%d", ch+1)
+}
+
+// インターリーブされた入力をモノラルに変換して追加
+func appendMono(dst []float32, interleaved []float32, channels int) []float32 {
+	if channels <= 1 {
+		return append(dst, interleaved...)
+	}
+	for i := 0; i+channels <= len(interleaved); i += channels {
+		var sum float32
+		for c := 0; c < channels; c++ {
+			sum += interleaved[i+c]
 		}
+		dst = append(dst, sum/float32(channels))
 	}
+	return dst
+}
 
-	return inputDevices, nil
+// 利用可能なオーディオデバイスを表示（選択番号は入力デバイス一覧の中での位置）
+func (app *App) PrintAudioDevices(devices []audio.DeviceInfo) {
+	fmt.Println(SectionHeader("利用可能なオーディオデバイス"))
+	for i, device := range devices {
+		fmt.Printf("  %s%d:%s %s%s%s (入力: %d, %.0f Hz)\n",
+			Bold+Green, i, Reset,
+			Cyan, device.Name, Reset,
+			device.MaxInputChannels, device.DefaultSampleRate)
+	}
 }
 
-// 処理ワーカーを実行
-func (app *App) ProcessingWorker(ctx context.Context) {
+// 処理ワーカーを開始
+// recordingDoneが閉じられるまで処理待ちのファイルを順に処理し、閉じられた後は残りをすべて処理して終了する
+// 録音の最後のセグメントを取りこぼさないよう、recordingDoneは録音を終えて残りのバッファを保存した後に閉じること
+func (app *App) StartProcessingWorker(recordingDone <-chan struct{}) {
 	app.WG.Add(1)
+	go app.processingWorker(recordingDone)
+}
+
+func (app *App) processingWorker(recordingDone <-chan struct{}) {
 	defer app.WG.Done()
 
 	for {
+		// 処理待ちファイルがあれば処理
+		if app.processNext() {
+			continue
+		}
 		select {
-		case <-ctx.Done():
+		case <-recordingDone:
 			// もし残りのファイルがあれば処理
-			for len(app.PendingFiles) > 0 {
-				app.Mutex.Lock()
-				if len(app.PendingFiles) > 0 {
-					filepath := app.PendingFiles[0]
-					app.PendingFiles = app.PendingFiles[1:]
-					app.Mutex.Unlock()
-					app.ProcessAudioFunc(app, filepath)
-				} else {
-					app.Mutex.Unlock()
-					break
-				}
-			}
+			app.ProcessPendingFiles()
 			return
-		default:
-			// 処理待ちファイルがあれば処理
-			app.Mutex.Lock()
-			if len(app.PendingFiles) > 0 {
-				filepath := app.PendingFiles[0]
-				app.PendingFiles = app.PendingFiles[1:]
-				app.Mutex.Unlock()
-				app.ProcessAudioFunc(app, filepath)
-			} else {
-				app.Mutex.Unlock()
-				// 少し待機
-				time.Sleep(500 * time.Millisecond)
-			}
+		case <-time.After(500 * time.Millisecond):
+			// 少し待機
 		}
 	}
 }
@@ -362,6 +734,6 @@ func (app *App) WaitForCompletion() {
 }
 
 // ProcessAudioFuncを設定
-func (app *App) SetProcessAudioFunc(fn func(*App, string)) {
+func (app *App) SetProcessAudioFunc(fn func(*App, Segment)) {
 	app.ProcessAudioFunc = fn
 }
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProcessingWorkerDrainsAfterRecording(t *testing.T) {
+	a := newTestApp(t)
+	var mu sync.Mutex
+	var processed []string
+	a.SetProcessAudioFunc(func(a *App, segment Segment) {
+		mu.Lock()
+		processed = append(processed, segment.Path)
+		mu.Unlock()
+	})
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(processed)
+	}
+	queue := func(path string) {
+		a.Mutex.Lock()
+		a.queueSegmentLocked(Segment{Path: path})
+		a.Mutex.Unlock()
+	}
+
+	recordingDone := make(chan struct{})
+	a.StartProcessingWorker(recordingDone)
+
+	// 録音中に区切ったセグメント
+	queue("recording_0001.wav")
+	for deadline := time.Now().Add(5 * time.Second); count() < 1; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("セグメントが処理されません")
+		}
+	}
+
+	// Ctrl+Cの後、録音ループが最後のバッファを保存してから録音の終了を知らせる
+	time.Sleep(600 * time.Millisecond)
+	queue("recording_0002.wav")
+	close(recordingDone)
+	a.WaitForCompletion()
+
+	if len(processed) != 2 || processed[1] != "recording_0002.wav" {
+		t.Errorf("処理したセグメント %v（最後のセグメントが処理されていません）", processed)
+	}
+}
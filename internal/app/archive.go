@@ -0,0 +1,48 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"whisper_local_faster_whsiper_go/internal/audio"
+)
+
+// 文字起こしが終わったセグメントのWAVをFLACに変換して保管し、WAVを削除する
+// FLACで可逆に保存できない浮動小数点や32bitのWAVはそのまま残す
+// 文字起こしに失敗したセグメントは再処理できるようWAVのまま残す
+func (app *App) archiveSegment(segment Segment) {
+	if !app.isTranscribed(segment) {
+		return
+	}
+
+	// 元のファイルがある場合、チャンネル別ファイルはwhisper用に分割したものなので保管しない
+	_, err := os.Stat(segment.Path)
+	hasBase := err == nil
+
+	for _, path := range app.segmentFiles(segment.Path) {
+		if !strings.EqualFold(filepath.Ext(path), ".wav") {
+			continue
+		}
+		if _, isChannel := audio.ChannelBasePath(path); isChannel && hasBase {
+			os.Remove(path)
+			continue
+		}
+
+		flacPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".flac"
+		if err := audio.ConvertToFlac(path, flacPath); err != nil {
+			os.Remove(flacPath)
+			if errors.Is(err, audio.ErrFlacLossy) {
+				// 浮動小数点や32bitのWAVは劣化させないようそのまま保管する
+				fmt.Printf("%s\n", InfoMessage("WAVのまま保管: "+path))
+				continue
+			}
+			fmt.Printf("%s\n", WarningMessage(fmt.Sprintf("FLACへの変換に失敗しました: %s (%v)", path, err)))
+			continue
+		}
+		os.Remove(path)
+		fmt.Printf("%s\n", InfoMessage("FLACで保管: "+flacPath))
+	}
+}
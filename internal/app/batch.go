@@ -0,0 +1,108 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"whisper_local_faster_whsiper_go/internal/audio"
+)
+
+// 指定されたファイルまたはディレクトリ内のWAV・FLACファイルを処理待ちリストに追加
+// ディレクトリはその直下の.wav・.flacファイルを名前順に追加する
+func (app *App) QueueFiles(paths []string) (int, error) {
+	files, err := collectAudioFiles(paths)
+	if err != nil {
+		return 0, err
+	}
+
+	app.Mutex.Lock()
+	app.queueFilesLocked(files)
+	app.Mutex.Unlock()
+
+	return len(files), nil
+}
+
+// 指定されたファイルとディレクトリ直下のWAV・FLACファイルを集める
+// チャンネル別のWAVファイルは元のセグメントのパスにまとめる
+func collectAudioFiles(paths []string) ([]string, error) {
+	files := make([]string, 0)
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("入力ファイルが見つかりません: %v", err)
+		}
+
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, fmt.Errorf("ディレクトリを読み込めませんでした: %v", err)
+		}
+		dirFiles := make([]string, 0)
+		seen := make(map[string]bool)
+		for _, entry := range entries {
+			path := filepath.Join(p, entry.Name())
+			if entry.IsDir() || !(strings.EqualFold(filepath.Ext(path), ".wav") || audio.IsFlac(path)) {
+				continue
+			}
+			// チャンネル別のWAVファイルは元のセグメント単位でまとめる
+			if !audio.IsFlac(path) {
+				path, _ = audio.ChannelBasePath(path)
+			}
+			if !seen[path] {
+				seen[path] = true
+				dirFiles = append(dirFiles, path)
+			}
+		}
+		sort.Strings(dirFiles)
+		files = append(files, dirFiles...)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("処理するWAV・FLACファイルがありません")
+	}
+	return files, nil
+}
+
+// 処理待ちリストが空になるまで順に処理
+func (app *App) ProcessPendingFiles() {
+	for app.processNext() {
+	}
+}
+
+// 処理待ちリストの先頭を1件処理（処理待ちがなければfalseを返す）
+func (app *App) processNext() bool {
+	app.Mutex.Lock()
+	if len(app.PendingFiles) == 0 {
+		app.Mutex.Unlock()
+		return false
+	}
+	segment := app.PendingFiles[0]
+	app.PendingFiles = app.PendingFiles[1:]
+	app.inFlight = segment.Path
+	app.Mutex.Unlock()
+
+	app.ProcessAudioFunc(app, segment)
+	if app.Retention.DeleteTranscribed {
+		app.deleteTranscribedSegment(segment)
+	} else if app.ArchiveFlac {
+		app.archiveSegment(segment)
+	}
+
+	// このセグメントの後に記録するメモを書き込む
+	app.Mutex.Lock()
+	notes := app.sessionNotes[segment.Path]
+	delete(app.sessionNotes, segment.Path)
+	app.inFlight = ""
+	app.Mutex.Unlock()
+	for _, note := range notes {
+		app.writeSessionNote(note)
+	}
+	return true
+}
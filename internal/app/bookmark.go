@@ -0,0 +1,92 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Bookmarkは録音中に重要な箇所として記録した位置
+type Bookmark struct {
+	Offset time.Duration // セッション開始からの時刻
+	Label  string        // 任意のラベル
+	Time   time.Time     // 記録した時刻
+}
+
+// 表示用の文字列（例: 00:12:41 予算の話）
+func (b Bookmark) String() string {
+	if b.Label == "" {
+		return FormatOffset(b.Offset)
+	}
+	return fmt.Sprintf("%s %s", FormatOffset(b.Offset), b.Label)
+}
+
+// 現在のセッション内の位置にブックマークを記録
+// 記録した位置を含むセグメントが保存されるときにそのセグメントに割り当てる
+func (app *App) AddBookmark(label string) Bookmark {
+	app.Mutex.Lock()
+	bookmark := Bookmark{
+		Offset: framesToDuration(app.capturedFrames, app.SampleRate),
+		Label:  strings.TrimSpace(label),
+		Time:   time.Now(),
+	}
+	app.Bookmarks = append(app.Bookmarks, bookmark)
+	app.pendingBookmarks = append(app.pendingBookmarks, bookmark)
+	app.Mutex.Unlock()
+
+	fmt.Printf("\r%s\n", InfoMessage("ブックマークを記録しました: "+bookmark.String()))
+	return bookmark
+}
+
+// 録音したフレームをセッションの時間軸に加える（ブックマークの位置に使う）
+func (app *App) advanceCaptured(frames int64) {
+	app.Mutex.Lock()
+	app.capturedFrames += frames
+	app.Mutex.Unlock()
+}
+
+// endより前の未割り当てのブックマークを取り出す（呼び出し側でロックを保持すること）
+func (app *App) takeBookmarksLocked(end int64) []Bookmark {
+	limit := framesToDuration(end, app.SampleRate)
+	var taken, rest []Bookmark
+	for _, bookmark := range app.pendingBookmarks {
+		if bookmark.Offset < limit {
+			taken = append(taken, bookmark)
+		} else {
+			rest = append(rest, bookmark)
+		}
+	}
+	app.pendingBookmarks = rest
+	return taken
+}
+
+// 分析用のヒントとして、ブックマークされたセグメントの文字起こしを記録
+func (app *App) AddBookmarkHint(segment Segment, transcript string) {
+	if len(segment.Bookmarks) == 0 {
+		return
+	}
+
+	labels := make([]string, len(segment.Bookmarks))
+	for i, bookmark := range segment.Bookmarks {
+		labels[i] = bookmark.String()
+	}
+
+	app.Mutex.Lock()
+	app.BookmarkHints = append(app.BookmarkHints,
+		fmt.Sprintf("[%s] %s", strings.Join(labels, ", "), transcript))
+	app.Mutex.Unlock()
+}
+
+// ブックマークの一覧（マークダウン用）
+func bookmarkList(bookmarks []Bookmark) string {
+	var list strings.Builder
+	for _, bookmark := range bookmarks {
+		list.WriteString(fmt.Sprintf("- ★ %s\n", bookmark.String()))
+	}
+	return list.String()
+}
+
+// セグメントのブックマーク一覧（マークダウン用）
+func (s Segment) BookmarkList() string {
+	return bookmarkList(s.Bookmarks)
+}
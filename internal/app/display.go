@@ -2,6 +2,7 @@ package app
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"time"
 	
@@ -36,13 +37,13 @@ const (
 	WhiteBg   = "\033[47m"
 )
 
-// 録音中のアニメーション
-func RecordingAnimation(stopCh <-chan struct{}) {
+// 録音中のアニメーション（入力レベルメーター付き）
+func RecordingAnimation(stopCh <-chan struct{}, meter *LevelMeter) {
 	spinner := "*"
 	for {
 		select {
 		case <-stopCh:
-			fmt.Print("\r" + strings.Repeat(" ", 50) + "\r") // クリア
+			fmt.Print("\r" + strings.Repeat(" ", 100) + "\r") // クリア
 			return
 		default:
 			// 録音中の表示
@@ -51,12 +52,86 @@ func RecordingAnimation(stopCh <-chan struct{}) {
 			// 経過時間などを追加
 			elapsed := time.Now().Format("15:04:05")
 			fmt.Printf("%s[%s]%s", Yellow, elapsed, Reset)
+
+			// 入力レベル
+			if meter != nil {
+				fmt.Printf(" %s", LevelDisplay(meter.Snapshot()))
+			}
 			
-			time.Sleep(500 * time.Millisecond)
+			time.Sleep(200 * time.Millisecond)
 		}
 	}
 }
 
+// 入力レベルの表示（レベルバー、クリッピング、無信号警告）
+func LevelDisplay(level LevelSnapshot) string {
+	var display strings.Builder
+
+	db := LevelDB(level.RMS)
+	display.WriteString(LevelBar(db, 20))
+	display.WriteString(fmt.Sprintf(" %6.1f dB", db))
+
+	if level.Clipping {
+		display.WriteString(" " + Bold + RedBg + White + " CLIP " + Reset)
+	}
+
+	if level.Dropped > 0 {
+		display.WriteString(fmt.Sprintf(" %s%s欠落 %dフレーム%s", Bold, Red, level.Dropped, Reset))
+	}
+
+	if level.SilentFor >= SilenceWarnTime {
+		display.WriteString(" " + WarningMessage(fmt.Sprintf("%d秒間 入力がありません（ミュートを確認）", int(level.SilentFor.Seconds()))))
+	}
+
+	// 前回の表示の残りを消す
+	display.WriteString("\033[K")
+	return display.String()
+}
+
+// RMSをdBFSに変換（下限-90dB）
+func LevelDB(rms float64) float64 {
+	if rms <= 0 {
+		return -90
+	}
+	db := 20 * math.Log10(rms)
+	if db < -90 {
+		return -90
+	}
+	return db
+}
+
+// -60dBから0dBの範囲をレベルバーとして描画
+func LevelBar(db float64, width int) string {
+	filled := int((db + 60) / 60 * float64(width))
+	if filled < 0 {
+		filled = 0
+	}
+	if filled > width {
+		filled = width
+	}
+
+	bar := strings.Builder{}
+	bar.WriteString("[")
+	for i := 0; i < width; i++ {
+		if i >= filled {
+			bar.WriteString("-")
+			continue
+		}
+		// 位置に応じて色を変える（-12dB以上は黄、-3dB以上は赤）
+		position := float64(i+1)/float64(width)*60 - 60
+		switch {
+		case position > -3:
+			bar.WriteString(Red + "|" + Reset)
+		case position > -12:
+			bar.WriteString(Yellow + "|" + Reset)
+		default:
+			bar.WriteString(Green + "|" + Reset)
+		}
+	}
+	bar.WriteString("]")
+	return bar.String()
+}
+
 // プログレスバーを生成
 func CreateProgressBar(total int, description string) *progressbar.ProgressBar {
 	return progressbar.NewOptions(total,
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"whisper_local_faster_whsiper_go/internal/audio"
+)
+
+// 画像の大きさ（ピクセル）
+const (
+	segmentImageWidth  = 800
+	sessionImageWidth  = 1200
+	waveformLaneHeight = 60  // 波形の1チャンネルあたりの高さ
+	spectrogramHeight  = 160 // スペクトログラムの高さ
+	overviewStep       = time.Second
+)
+
+// Imagesはマークダウンに貼る波形とスペクトログラム（マークダウンからの相対パス）
+type Images struct {
+	Waveform    string
+	Spectrogram string
+}
+
+// 画像を保存するディレクトリ（マークダウンファイルごと）
+func (app *App) imagesDir() string {
+	return strings.TrimSuffix(app.MdFile, filepath.Ext(app.MdFile)) + "_images"
+}
+
+// Markdownは画像へのリンクを返す
+func (images Images) Markdown() string {
+	return fmt.Sprintf("![波形](%s)\n\n![スペクトログラム](%s)\n", images.Waveform, images.Spectrogram)
+}
+
+// RenderSegmentImagesはセグメントの波形とスペクトログラムを保存し、セッション全体の概要にも追加する
+func (app *App) RenderSegmentImages(segment Segment) (Images, error) {
+	samples, format, err := readSegmentAudio(segment.Path)
+	if err != nil {
+		return Images{}, err
+	}
+	if len(samples) < format.Channels {
+		return Images{}, fmt.Errorf("音声が空です: %s", segment.Path)
+	}
+
+	// セッション全体の概要（録音されていない区間は空ける）
+	app.overviewMutex.Lock()
+	if app.overview == nil {
+		app.overview = audio.NewOverview(overviewStep)
+	}
+	if segment.HasTimeline() {
+		if gap := segment.StartOffset() - app.overview.Duration(); gap >= overviewStep {
+			app.overview.AddGap(gap)
+		}
+	}
+	app.overview.Add(samples, format.Channels, format.SampleRate)
+	app.overviewMutex.Unlock()
+
+	visual := audio.Analyze(samples, format.Channels, format.SampleRate, segmentImageWidth)
+	if segment.HasTimeline() {
+		visual.Start = segment.StartOffset()
+	}
+	name := strings.TrimSuffix(filepath.Base(segment.Path), filepath.Ext(segment.Path))
+	return app.saveImages(visual, name, segmentImageWidth, waveformLaneHeight*format.Channels)
+}
+
+// セッション全体の波形とスペクトログラムを保存（セグメントがない場合はfalse）
+func (app *App) renderSessionImages() (Images, bool) {
+	app.overviewMutex.Lock()
+	defer app.overviewMutex.Unlock()
+	if app.overview == nil || app.overview.Duration() == 0 {
+		return Images{}, false
+	}
+
+	images, err := app.saveImages(app.overview.Visualization(), "session", sessionImageWidth, waveformLaneHeight*2)
+	if err != nil {
+		fmt.Printf("  %v\n", err)
+		return Images{}, false
+	}
+	return images, true
+}
+
+// 波形（SVG）とスペクトログラム（PNG）を画像ディレクトリに保存する
+func (app *App) saveImages(visual *audio.Visualization, name string, width, waveformHeight int) (Images, error) {
+	dir := app.imagesDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Images{}, fmt.Errorf("画像ディレクトリを作成できませんでした: %v", err)
+	}
+
+	waveform := name + "_waveform.svg"
+	spectrogram := name + "_spectrogram.png"
+	if err := audio.SaveWaveformSVG(filepath.Join(dir, waveform), visual, width, waveformHeight+16); err != nil {
+		return Images{}, err
+	}
+	if err := audio.SaveSpectrogramPNG(filepath.Join(dir, spectrogram), visual, width, spectrogramHeight); err != nil {
+		return Images{}, err
+	}
+
+	base := filepath.Base(dir)
+	return Images{Waveform: base + "/" + waveform, Spectrogram: base + "/" + spectrogram}, nil
+}
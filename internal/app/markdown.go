@@ -6,6 +6,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"whisper_local_faster_whsiper_go/internal/audio"
 )
 
 // マークダウンファイルを初期化
@@ -24,13 +26,33 @@ func (app *App) InitializeMarkdownFile() {
 
 	startTime := time.Now().Format("2006-01-02 15:04:05")
 	writer.WriteString(fmt.Sprintf("**録音開始時刻**: %s\n\n", startTime))
+	writer.WriteString(fmt.Sprintf("**セッションID**: %s\n\n", app.SessionID))
+	writer.WriteString("各セグメントの見出しは録音開始からの経過時刻（時:分:秒）です。\n\n")
 
 	if app.DeviceName != "" {
 		writer.WriteString(fmt.Sprintf("**録音デバイス**: %s\n\n", app.DeviceName))
 	}
 
+	if app.TranscriberName != "" {
+		writer.WriteString(fmt.Sprintf("**文字起こし**: %s\n\n", app.TranscriberName))
+	}
 	writer.WriteString(fmt.Sprintf("**サンプリングレート**: %d Hz\n\n", app.SampleRate))
-	writer.WriteString(fmt.Sprintf("**録音間隔**: %.1f 秒\n\n", app.RecordInterval))
+	if app.WhisperReady {
+		writer.WriteString(fmt.Sprintf("**保存形式**: %d Hz %s（whisper用に変換）\n\n", audio.WhisperSampleRate, app.storedChannelsLabel()))
+	} else {
+		writer.WriteString(fmt.Sprintf("**保存形式**: %d Hz %s\n\n", app.SampleRate, app.storedChannelsLabel()))
+	}
+	sampleFormat := app.WavOptions.Format.String()
+	if app.WavOptions.Dither && app.WavOptions.Format != audio.Float32 {
+		sampleFormat += "（TPDFディザー）"
+	}
+	writer.WriteString(fmt.Sprintf("**サンプル形式**: %s\n\n", sampleFormat))
+	if app.UseVAD {
+		writer.WriteString(fmt.Sprintf("**区切り**: 発話の切れ目 (%.1f〜%.1f 秒)\n\n",
+			app.VAD.MinSegment.Seconds(), app.VAD.MaxSegment.Seconds()))
+	} else {
+		writer.WriteString(fmt.Sprintf("**録音間隔**: %.1f 秒\n\n", app.RecordInterval))
+	}
 	writer.WriteString(fmt.Sprintf("**Ollamaモデル**: %s\n\n", OllamaModel))
 	writer.WriteString("---\n\n")
 	writer.Flush()
@@ -38,6 +60,21 @@ func (app *App) InitializeMarkdownFile() {
 	fmt.Printf("  ファイル初期化: %s\n", app.MdFile)
 }
 
+// 保存するファイルのチャンネル構成（チャンネルモードとチャンネル数から決まる）
+func (app *App) storedChannelsLabel() string {
+	channels := app.bufferChannels()
+	switch {
+	case channels == 1 && app.Channels > 1:
+		return fmt.Sprintf("モノラル（%dチャンネルを混合）", app.Channels)
+	case channels == 1:
+		return "モノラル"
+	case app.ChannelMode == ChannelModeSplit:
+		return fmt.Sprintf("チャンネル別のモノラル（%dファイル）", channels)
+	default:
+		return fmt.Sprintf("%dチャンネル", channels)
+	}
+}
+
 // 録音終了の記録を追加
 func (app *App) AddRecordingEndNote() {
 	// 終了時刻
@@ -54,12 +91,44 @@ func (app *App) AddRecordingEndNote() {
 	content.WriteString(fmt.Sprintf("\n## 録音終了: %s\n\n", endTime))
 	content.WriteString("### 録音セッション統計\n\n")
 	content.WriteString(fmt.Sprintf("- 総セグメント数: %d\n", len(app.AllTranscripts)))
+	if app.timelineFrames > 0 {
+		content.WriteString(fmt.Sprintf("- 録音時間: %s\n", FormatOffset(framesToDuration(app.timelineFrames, app.SampleRate))))
+	}
+	if app.TotalDropped > 0 {
+		content.WriteString(fmt.Sprintf("- 欠落した音声: %d フレーム（%.2f 秒）\n",
+			app.TotalDropped, float64(app.TotalDropped)/float64(app.SampleRate)))
+	}
+	if app.TotalClipped > 0 {
+		content.WriteString(fmt.Sprintf("- クリップしたサンプル数: %d\n", app.TotalClipped))
+	}
+	if app.DeviceFailures > 0 {
+		content.WriteString(fmt.Sprintf("- 入力デバイスの切断回数: %d\n", app.DeviceFailures))
+	}
+	if app.PauseCount > 0 {
+		content.WriteString(fmt.Sprintf("- 一時停止回数: %d\n", app.PauseCount))
+	}
+	if app.SilentSegments > 0 {
+		content.WriteString(fmt.Sprintf("- 無音でスキップしたセグメント数: %d\n", app.SilentSegments))
+	}
+	if len(app.Bookmarks) > 0 {
+		content.WriteString(fmt.Sprintf("- ブックマーク数: %d\n", len(app.Bookmarks)))
+	}
 
 	totalChars := 0
 	for _, t := range app.AllTranscripts {
 		totalChars += len(t)
 	}
 	content.WriteString(fmt.Sprintf("- 合計文字数: %d\n", totalChars))
+
+	if len(app.Bookmarks) > 0 {
+		content.WriteString("\n### ブックマーク\n\n")
+		content.WriteString(bookmarkList(app.Bookmarks))
+	}
+	if images, ok := app.renderSessionImages(); ok {
+		content.WriteString("\n### セッション全体の波形とスペクトログラム\n\n")
+		content.WriteString("灰色の区間は録音していない時間（一時停止・無音でスキップ・欠落）です。\n\n")
+		content.WriteString(images.Markdown())
+	}
 	content.WriteString("\n---\n\n")
 
 	if _, err = file.WriteString(content.String()); err != nil {
@@ -69,3 +138,39 @@ func (app *App) AddRecordingEndNote() {
 
 	fmt.Printf("  録音終了記録: %s\n", app.MdFile)
 }
+
+// セッションのメモ（無音・一時停止など）をそのまま追記
+func (app *App) writeSessionNote(note string) {
+	file, err := os.OpenFile(app.MdFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Printf("  マークダウンファイルを開けませんでした: %v\n", err)
+		return
+	}
+	defer file.Close()
+
+	if _, err = file.WriteString(note); err != nil {
+		fmt.Printf("  マークダウンファイルへの書き込みエラー: %v\n", err)
+	}
+}
+
+// 無音セグメントのメモ（start/endはセッション開始からの時刻）
+func silenceNote(start, end time.Duration, duration float64, bookmarks []Bookmark) string {
+	note := fmt.Sprintf("\n## %s–%s\n\n*（無音: %.1f 秒、文字起こしをスキップしました）*\n\n",
+		FormatOffset(start), FormatOffset(end), duration)
+	if len(bookmarks) > 0 {
+		note += "### ブックマーク\n\n" + bookmarkList(bookmarks) + "\n"
+	}
+	return note + "---\n"
+}
+
+// 一時停止・再開のメモ（offsetはセッション開始からの時刻）
+func pauseNote(label string, offset time.Duration) string {
+	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	return fmt.Sprintf("\n**%s**: %s（%s）\n\n---\n", label, FormatOffset(offset), timestamp)
+}
+
+// 入力デバイスの切断・復帰のメモ（offsetはセッション開始からの時刻）
+func deviceNote(message string, offset time.Duration) string {
+	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	return fmt.Sprintf("\n**%s**（%s）: %s\n\n---\n", FormatOffset(offset), timestamp, message)
+}
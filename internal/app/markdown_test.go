@@ -0,0 +1,44 @@
+package app
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestStoredChannelsLabel(t *testing.T) {
+	tests := []struct {
+		mode     string
+		channels int
+		want     string
+	}{
+		{ChannelModeMix, 1, "モノラル"},
+		{ChannelModeMix, 2, "モノラル（2チャンネルを混合）"},
+		{ChannelModeInterleaved, 1, "モノラル"},
+		{ChannelModeInterleaved, 4, "4チャンネル"},
+		{ChannelModeSplit, 2, "チャンネル別のモノラル（2ファイル）"},
+	}
+	for _, tt := range tests {
+		a := &App{ChannelMode: tt.mode, Channels: tt.channels}
+		if got := a.storedChannelsLabel(); got != tt.want {
+			t.Errorf("%s・%dチャンネル: %q（期待値 %q）", tt.mode, tt.channels, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownHeaderChannels(t *testing.T) {
+	a := newTestApp(t)
+	a.ChannelMode = ChannelModeInterleaved
+	a.Channels = 2
+	a.SampleRate = 48000
+	a.WhisperReady = true
+	a.InitializeMarkdownFile()
+
+	data, err := os.ReadFile(a.MdFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "**保存形式**: 16000 Hz 2チャンネル（whisper用に変換）") {
+		t.Errorf("保存形式が見つかりません:\n%s", data)
+	}
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"sync"
+	"time"
+)
+
+// 入力レベル判定の閾値
+const (
+	ClipLevel       = 0.999           // クリッピングとみなすピーク値
+	SilenceLevel    = 0.0005          // 無信号とみなすRMS（約-66dBFS）
+	ClipHoldTime    = 2 * time.Second // クリッピング表示を保持する時間
+	SilenceWarnTime = 5 * time.Second // 無信号警告を出すまでの時間
+)
+
+// LevelMeterは録音ループで計測した入力レベルを表示側に渡す
+type LevelMeter struct {
+	mu         sync.Mutex
+	rms        float64
+	peak       float64
+	lastClip   time.Time
+	lastSignal time.Time
+	dropped    int64
+}
+
+// 新しいレベルメーターを作成
+func NewLevelMeter() *LevelMeter {
+	return &LevelMeter{lastSignal: time.Now()}
+}
+
+// 読み取ったバッファのRMSとピークを記録
+func (m *LevelMeter) Update(rms, peak float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.rms = rms
+	m.peak = peak
+	if peak >= ClipLevel {
+		m.lastClip = now
+	}
+	if rms >= SilenceLevel {
+		m.lastSignal = now
+	}
+}
+
+// 欠落したフレーム数を加算
+func (m *LevelMeter) AddDropped(frames int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dropped += frames
+}
+
+// セグメントの区切りで欠落フレーム数をリセット
+func (m *LevelMeter) ResetDropped() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dropped = 0
+}
+
+// 無信号の計測をリセット（録音開始・再開時）
+func (m *LevelMeter) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rms = 0
+	m.peak = 0
+	m.lastSignal = time.Now()
+}
+
+// LevelSnapshotは表示用の入力レベル
+type LevelSnapshot struct {
+	RMS       float64       // 直近のRMS
+	Peak      float64       // 直近のピーク
+	Clipping  bool          // 直近にクリッピングがあったか
+	SilentFor time.Duration // 無信号が続いている時間
+	Dropped   int64         // 現在のセグメントで欠落したフレーム数
+}
+
+// 現在の入力レベルを取得
+func (m *LevelMeter) Snapshot() LevelSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	return LevelSnapshot{
+		RMS:       m.rms,
+		Peak:      m.peak,
+		Clipping:  !m.lastClip.IsZero() && now.Sub(m.lastClip) < ClipHoldTime,
+		SilentFor: now.Sub(m.lastSignal),
+		Dropped:   m.dropped,
+	}
+}
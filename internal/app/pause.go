@@ -0,0 +1,51 @@
+package app
+
+// 録音を一時停止（録音ループが現在のセグメントを保存して入力の記録を止める）
+func (app *App) Pause() {
+	if !app.paused.Swap(true) {
+		app.Mutex.Lock()
+		app.PauseCount++
+		app.Mutex.Unlock()
+	}
+}
+
+// 録音を再開
+func (app *App) Resume() {
+	app.paused.Store(false)
+}
+
+// 一時停止と再開を切り替え、切り替え後に一時停止中ならtrueを返す
+func (app *App) TogglePause() bool {
+	if app.IsPaused() {
+		app.Resume()
+		return false
+	}
+	app.Pause()
+	return true
+}
+
+// 一時停止中かどうか
+func (app *App) IsPaused() bool {
+	return app.paused.Load()
+}
+
+// セッションのメモをマークダウンに記録
+// 処理待ちや処理中のセグメントがある場合は、その文字起こしの後に書き込む
+func (app *App) AddSessionNote(note string) {
+	app.Mutex.Lock()
+	deferred := app.deferSessionNoteLocked(note)
+	app.Mutex.Unlock()
+
+	if !deferred {
+		app.writeSessionNote(note)
+	}
+}
+
+// 処理待ちのセグメントがあればメモをその後ろに回す（呼び出し側でロックを保持すること）
+func (app *App) deferSessionNoteLocked(note string) bool {
+	if (len(app.PendingFiles) == 0 && app.inFlight == "") || app.lastQueued == "" {
+		return false
+	}
+	app.sessionNotes[app.lastQueued] = append(app.sessionNotes[app.lastQueued], note)
+	return true
+}
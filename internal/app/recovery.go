@@ -0,0 +1,137 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"whisper_local_faster_whsiper_go/internal/audio"
+)
+
+// 録音中のジャーナルファイルの接頭辞
+// 正常に区切られたセグメントのジャーナルは削除されるため、起動時に残っていれば強制終了の跡
+const journalPrefix = "journal_"
+
+// 録音中の音声を逐次書き込むジャーナルファイルを開く
+func (app *App) openJournal() {
+	timestamp := time.Now().Format("20060102_150405")
+	path := filepath.Join(app.RecordingDir, fmt.Sprintf("%s%s.wav", journalPrefix, timestamp))
+
+	writer, err := audio.NewStreamWavWriter(path, app.SampleRate, app.bufferChannels())
+	if err != nil {
+		fmt.Printf("\r%s\n", WarningMessage("ジャーナルファイルを作成できませんでした: "+err.Error()))
+		return
+	}
+	app.journal = writer
+	app.journalPath = path
+}
+
+// ジャーナルにサンプルを追記
+func (app *App) writeJournal(samples []float32) {
+	if app.journal == nil || len(samples) == 0 {
+		return
+	}
+	if err := app.journal.Write(samples); err != nil {
+		fmt.Printf("\r%s\n", WarningMessage("ジャーナル書き込みエラー: "+err.Error()))
+	}
+}
+
+// セグメントを保存した後、ジャーナルを閉じて削除する
+func (app *App) closeJournal() {
+	if app.journal == nil {
+		return
+	}
+	app.journal.Close()
+	os.Remove(app.journalPath)
+	app.journal = nil
+	app.journalPath = ""
+}
+
+// 強制終了などで残った録音ファイルを修復し、処理待ちリストに追加する
+// ジャーナルはセグメントと同じ形式に変換してから追加する
+func (app *App) RecoverRecordings() int {
+	entries, err := os.ReadDir(app.RecordingDir)
+	if err != nil {
+		return 0
+	}
+
+	names := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".wav") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	recovered := make([]string, 0)
+	for _, name := range names {
+		path := filepath.Join(app.RecordingDir, name)
+		isJournal := strings.HasPrefix(name, journalPrefix)
+
+		repaired, err := audio.RepairWav(path)
+		if err != nil {
+			fmt.Printf("%s\n", WarningMessage(fmt.Sprintf("録音ファイルを修復できませんでした: %s (%v)", name, err)))
+			continue
+		}
+		if !isJournal && !repaired {
+			continue
+		}
+
+		if isJournal {
+			path, err = app.convertJournal(path)
+			if err != nil {
+				fmt.Printf("%s\n", WarningMessage(fmt.Sprintf("ジャーナルを変換できませんでした: %s (%v)", name, err)))
+				continue
+			}
+			if path == "" {
+				continue
+			}
+		}
+
+		fmt.Printf("%s\n", SuccessMessage("中断された録音を復旧: "+path))
+		recovered = append(recovered, path)
+	}
+
+	if len(recovered) > 0 {
+		app.Mutex.Lock()
+		app.queueFilesLocked(recovered)
+		app.Mutex.Unlock()
+	}
+	return len(recovered)
+}
+
+// ジャーナルを文字起こし用のセグメントファイルに変換し、ジャーナルを削除する
+// 音声が含まれていない場合は空文字を返す
+func (app *App) convertJournal(journalPath string) (string, error) {
+	samples, format, err := audio.ReadWav(journalPath)
+	if err != nil {
+		return "", err
+	}
+	channels, sampleRate := format.Channels, format.SampleRate
+	if len(samples) < channels {
+		os.Remove(journalPath)
+		return "", nil
+	}
+
+	// whisper用に16kHzへ変換
+	if app.WhisperReady && sampleRate != audio.WhisperSampleRate {
+		perChannel := audio.Deinterleave(samples, channels)
+		for c := range perChannel {
+			perChannel[c] = audio.Resample(perChannel[c], sampleRate, audio.WhisperSampleRate)
+		}
+		samples = audio.Interleave(perChannel)
+		sampleRate = audio.WhisperSampleRate
+	}
+
+	timestamp := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(journalPath), journalPrefix), filepath.Ext(journalPath))
+	path := filepath.Join(app.RecordingDir, fmt.Sprintf("recording_%s_recovered.wav", timestamp))
+	if err := audio.SaveAsWavChannels(path, [][]float32{samples}, sampleRate, channels); err != nil {
+		return "", err
+	}
+
+	os.Remove(journalPath)
+	return path, nil
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"whisper_local_faster_whsiper_go/internal/audio"
+)
+
+func TestRecoverRecordings(t *testing.T) {
+	a := newTestApp(t)
+	a.SampleRate = 48000
+	a.Channels = 2
+	a.ChannelMode = ChannelModeInterleaved
+	a.WhisperReady = true
+
+	// 正常に保存されたセグメントは復旧の対象にしない
+	saved := filepath.Join(a.RecordingDir, "recording_20250101_120000_0001.wav")
+	if _, err := audio.WriteWavFile(saved, [][]float32{make([]float32, 3200)}, 16000, 2, audio.WavOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1.5秒録音したところで強制終了した（ヘッダーは1秒の時点で同期したまま）
+	a.openJournal()
+	if a.journal == nil {
+		t.Fatal("ジャーナルを作成できませんでした")
+	}
+	journalPath := a.journalPath
+	for i := 0; i < 15; i++ {
+		a.writeJournal(make([]float32, 4800*2))
+	}
+	a.journal, a.journalPath = nil, ""
+
+	if count := a.RecoverRecordings(); count != 1 {
+		t.Fatalf("復旧した録音 %d 件（期待値 1 件）", count)
+	}
+	if _, err := os.Stat(journalPath); !os.IsNotExist(err) {
+		t.Errorf("ジャーナルが残っています: %s", journalPath)
+	}
+	if len(a.PendingFiles) != 1 {
+		t.Fatalf("処理待ち %+v", a.PendingFiles)
+	}
+
+	// whisper用に16kHzへ変換し、同期していなかった0.5秒も含める
+	_, format, err := audio.ReadWav(a.PendingFiles[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format.SampleRate != audio.WhisperSampleRate || format.Channels != 2 || format.Frames != 24000 {
+		t.Errorf("復旧したセグメントの形式 %s・%d フレーム（期待値 16000 Hz・2ch・24000 フレーム）", format, format.Frames)
+	}
+}
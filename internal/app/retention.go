@@ -0,0 +1,356 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 文字起こし済みの録音ファイルの一覧（RecordingDir内）
+const transcribedManifest = "transcribed.txt"
+
+// RetentionPolicyは録音と文字起こし結果の保存ルール
+// 0の項目は制限しない
+type RetentionPolicy struct {
+	MaxTotalSize      int64         // RecordingDirとTranscriptsDirの合計サイズの上限（バイト）
+	MaxAge            time.Duration // これより古いファイルを削除する
+	DeleteTranscribed bool          // 文字起こしが終わった録音を削除する
+}
+
+// 何らかの制限が設定されているか
+func (p RetentionPolicy) Enabled() bool {
+	return p.MaxTotalSize > 0 || p.MaxAge > 0 || p.DeleteTranscribed
+}
+
+// CleanupResultは保存ルールを適用した結果
+type CleanupResult struct {
+	Deleted    int   // 削除したファイル数
+	FreedBytes int64 // 削除したファイルの合計サイズ
+	Kept       int   // 処理待ち・文字起こし前などのため残したファイル数
+	TotalBytes int64 // 適用後の合計サイズ
+}
+
+// 保存ルールの対象となるファイル
+type retainedFile struct {
+	path      string
+	size      int64
+	modTime   time.Time
+	recording bool // 録音ファイル（文字起こし結果ではない）
+}
+
+// 録音を文字起こし済みとして記録する
+// 削除の設定がある場合は処理後に音声を削除し、cleanupでも削除の対象にする
+func (app *App) MarkTranscribed(segment Segment) {
+	if !app.inRecordingDir(segment.Path) {
+		return
+	}
+
+	app.Mutex.Lock()
+	defer app.Mutex.Unlock()
+	name := segmentName(segment.Path)
+	app.transcribed[name] = true
+
+	file, err := os.OpenFile(filepath.Join(app.RecordingDir, transcribedManifest), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Printf("%s\n", WarningMessage("文字起こし済みの記録に失敗しました: "+err.Error()))
+		return
+	}
+	defer file.Close()
+	fmt.Fprintln(file, name)
+}
+
+// 文字起こしが終わったセグメントの音声を削除
+func (app *App) deleteTranscribedSegment(segment Segment) {
+	if !app.isTranscribed(segment) {
+		return
+	}
+
+	for _, path := range app.segmentFiles(segment.Path) {
+		if err := os.Remove(path); err == nil {
+			fmt.Printf("%s\n", InfoMessage("文字起こし済みの録音を削除: "+path))
+		}
+	}
+}
+
+// セグメントの文字起こしが終わっているか
+func (app *App) isTranscribed(segment Segment) bool {
+	app.Mutex.Lock()
+	defer app.Mutex.Unlock()
+	return app.transcribed[segmentName(segment.Path)]
+}
+
+// 保存ルールをRecordingDirとTranscriptsDirに適用する
+// 処理待ち・処理中のセグメント、録音中のジャーナル、現在のマークダウンファイルは削除しない
+// 別のプロセス（cleanupコマンド）からは処理待ちの一覧が分からないため、
+// transcribed.txtにない録音は合計サイズの上限では削除せず、MaxAgeより古い場合だけ削除する
+func (app *App) ApplyRetention(policy RetentionPolicy) (CleanupResult, error) {
+	var result CleanupResult
+
+	files, err := app.retainedFiles()
+	if err != nil {
+		return result, err
+	}
+	protected := app.protectedFiles()
+	transcribed := app.loadTranscribed()
+
+	// 古いものから削除するため更新日時順に並べる
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	for _, file := range files {
+		result.TotalBytes += file.size
+	}
+
+	untranscribed := func(file retainedFile) bool {
+		return file.recording && !transcribed[segmentName(file.path)]
+	}
+	remove := func(file retainedFile) bool {
+		if protected[file.path] {
+			result.Kept++
+			return false
+		}
+		if err := os.Remove(file.path); err != nil {
+			fmt.Printf("%s\n", WarningMessage(fmt.Sprintf("削除できませんでした: %s (%v)", file.path, err)))
+			return false
+		}
+		result.Deleted++
+		result.FreedBytes += file.size
+		result.TotalBytes -= file.size
+		return true
+	}
+
+	cutoff := time.Now().Add(-policy.MaxAge)
+	remaining := files[:0]
+	for _, file := range files {
+		switch {
+		case policy.MaxAge > 0 && file.modTime.Before(cutoff):
+		case policy.DeleteTranscribed && file.recording && transcribed[segmentName(file.path)]:
+		default:
+			remaining = append(remaining, file)
+			continue
+		}
+		if !remove(file) {
+			remaining = append(remaining, file)
+		}
+	}
+
+	// 合計サイズが上限を超えている間は古いものから削除（文字起こし前の録音は残す）
+	if policy.MaxTotalSize > 0 {
+		for _, file := range remaining {
+			if result.TotalBytes <= policy.MaxTotalSize {
+				break
+			}
+			if untranscribed(file) {
+				result.Kept++
+				continue
+			}
+			remove(file)
+		}
+	}
+
+	app.pruneTranscribed(transcribed)
+	return result, nil
+}
+
+// RecordingDirとTranscriptsDirの削除対象になりうるファイル
+func (app *App) retainedFiles() ([]retainedFile, error) {
+	files := make([]retainedFile, 0)
+	dirs := []struct {
+		dir       string
+		recording bool
+	}{
+		{app.RecordingDir, true},
+		{app.TranscriptsDir, false},
+	}
+
+	for _, d := range dirs {
+		err := filepath.WalkDir(d.dir, func(path string, entry os.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if entry.IsDir() || entry.Name() == transcribedManifest {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil
+			}
+			files = append(files, retainedFile{path: path, size: info.Size(), modTime: info.ModTime(), recording: d.recording})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ディレクトリを読み込めませんでした: %v", err)
+		}
+	}
+	return files, nil
+}
+
+// 削除してはいけないファイル（処理待ち・処理中のセグメントとその関連ファイル、ジャーナル、マークダウン）
+func (app *App) protectedFiles() map[string]bool {
+	app.Mutex.Lock()
+	segments := make([]string, 0, len(app.PendingFiles)+1)
+	for _, segment := range app.PendingFiles {
+		segments = append(segments, segment.Path)
+	}
+	if app.inFlight != "" {
+		segments = append(segments, app.inFlight)
+	}
+	app.Mutex.Unlock()
+
+	protected := map[string]bool{app.MdFile: true}
+	for _, path := range segments {
+		protected[path] = true
+		for _, related := range app.segmentFiles(path) {
+			protected[related] = true
+		}
+	}
+
+	// ジャーナルは次回の起動時に復旧するため残す
+	journals, _ := filepath.Glob(filepath.Join(app.RecordingDir, journalPrefix+"*"))
+	for _, path := range journals {
+		protected[path] = true
+	}
+	return protected
+}
+
+// セグメントに関連するファイル（チャンネル別ファイル、元の音声、whisperの出力）
+func (app *App) segmentFiles(path string) []string {
+	if !app.inRecordingDir(path) {
+		return nil
+	}
+
+	name := segmentName(path)
+	files := make([]string, 0)
+	for _, dir := range []string{filepath.Dir(path), filepath.Join(app.RecordingDir, "original")} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && segmentName(entry.Name()) == name {
+				files = append(files, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	return files
+}
+
+// ファイル名からセグメント名を取り出す（拡張子とチャンネル番号を除く）
+// 例: recording_20250101_120000_0001_ch1.wav.txt → recording_20250101_120000_0001
+func segmentName(path string) string {
+	name := filepath.Base(path)
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, "_ch"); i >= 0 {
+		if _, err := strconv.Atoi(name[i+3:]); err == nil {
+			name = name[:i]
+		}
+	}
+	return name
+}
+
+// パスがRecordingDirの中にあるか（取り込んだファイルは削除しない）
+func (app *App) inRecordingDir(path string) bool {
+	rel, err := filepath.Rel(app.RecordingDir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// 文字起こし済みの録音のセグメント名を読み込む
+func (app *App) loadTranscribed() map[string]bool {
+	transcribed := make(map[string]bool)
+	file, err := os.Open(filepath.Join(app.RecordingDir, transcribedManifest))
+	if err != nil {
+		return transcribed
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" {
+			transcribed[segmentName(name)] = true
+		}
+	}
+	return transcribed
+}
+
+// 削除済みの録音を文字起こし済みの一覧から取り除く
+func (app *App) pruneTranscribed(transcribed map[string]bool) {
+	if len(transcribed) == 0 {
+		return
+	}
+
+	exists := make(map[string]bool)
+	entries, _ := os.ReadDir(app.RecordingDir)
+	for _, entry := range entries {
+		exists[segmentName(entry.Name())] = true
+	}
+
+	names := make([]string, 0, len(transcribed))
+	for name := range transcribed {
+		if exists[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	app.Mutex.Lock()
+	defer app.Mutex.Unlock()
+	path := filepath.Join(app.RecordingDir, transcribedManifest)
+	if len(names) == 0 {
+		os.Remove(path)
+		return
+	}
+	os.WriteFile(path, []byte(strings.Join(names, "\n")+"\n"), 0644)
+}
+
+// サイズの指定を解釈（例: 500MB, 2GB, 1048576）
+func ParseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"TB", 1 << 40},
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(s, unit.suffix) {
+			multiplier = unit.size
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("サイズの指定が不正です: %q", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// サイズを読みやすい単位で表示
+func FormatSize(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(size)/(1<<30))
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// テスト用のディレクトリを使うアプリケーション
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+	dir := t.TempDir()
+	a := &App{
+		RecordingDir:   filepath.Join(dir, "recordings"),
+		TranscriptsDir: filepath.Join(dir, "transcripts"),
+		MdFile:         filepath.Join(dir, "transcripts", "test_all_communication.md"),
+		transcribed:    make(map[string]bool),
+		sessionNotes:   make(map[string][]string),
+	}
+	os.MkdirAll(a.RecordingDir, 0755)
+	os.MkdirAll(a.TranscriptsDir, 0755)
+	return a
+}
+
+func writeTestFile(t *testing.T, path string, size int, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApplyRetentionKeepsUntranscribed(t *testing.T) {
+	a := newTestApp(t)
+	old := time.Now().Add(-48 * time.Hour)
+
+	done := filepath.Join(a.RecordingDir, "recording_20250101_120000_0001.wav")
+	doneText := done + ".txt"
+	pending := filepath.Join(a.RecordingDir, "recording_20250101_120030_0002.wav")
+	pendingChannel := filepath.Join(a.RecordingDir, "recording_20250101_120030_0002_ch1.wav")
+	transcript := filepath.Join(a.TranscriptsDir, "20250101_1200_all_communication.md")
+	for _, path := range []string{done, doneText, pending, pendingChannel, transcript} {
+		writeTestFile(t, path, 1000, old)
+	}
+
+	// cleanupコマンドと同じく処理待ちの一覧が空の状態で、文字起こし済みの記録だけがある
+	os.WriteFile(filepath.Join(a.RecordingDir, transcribedManifest), []byte("recording_20250101_120000_0001\n"), 0644)
+
+	result, err := a.ApplyRetention(RetentionPolicy{MaxTotalSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{done, doneText, transcript} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("削除されていません: %s", path)
+		}
+	}
+	for _, path := range []string{pending, pendingChannel} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("文字起こし前の録音が削除されました: %s", path)
+		}
+	}
+	if result.Deleted != 3 || result.Kept != 2 {
+		t.Errorf("削除 %d 件・残し %d 件（期待値 3 件・2 件）", result.Deleted, result.Kept)
+	}
+}
+
+func TestApplyRetentionMaxAgeUntranscribed(t *testing.T) {
+	a := newTestApp(t)
+	old := time.Now().Add(-48 * time.Hour)
+
+	// このシリーズより前の録音や文字起こしに失敗した録音はtranscribed.txtにないが、MaxAgeより古ければ削除する
+	stale := filepath.Join(a.RecordingDir, "recording_20250101_120000_0001.wav")
+	stray := filepath.Join(a.RecordingDir, "memo.txt")
+	queued := filepath.Join(a.RecordingDir, "recording_20250101_120030_0002.wav")
+	journal := filepath.Join(a.RecordingDir, journalPrefix+"20250101_120100.wav")
+	recent := filepath.Join(a.RecordingDir, "recording_20250102_120000_0001.wav")
+	for _, path := range []string{stale, stray, queued, journal} {
+		writeTestFile(t, path, 1000, old)
+	}
+	writeTestFile(t, recent, 1000, time.Now())
+	a.PendingFiles = []Segment{{Path: queued}}
+
+	result, err := a.ApplyRetention(RetentionPolicy{MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{stale, stray} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("古い録音が削除されていません: %s", path)
+		}
+	}
+	for _, path := range []string{queued, journal, recent} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("削除されました: %s", path)
+		}
+	}
+	if result.Deleted != 2 || result.Kept != 2 {
+		t.Errorf("削除 %d 件・残し %d 件（期待値 2 件・2 件）", result.Deleted, result.Kept)
+	}
+}
+
+func TestApplyRetentionDeleteTranscribed(t *testing.T) {
+	a := newTestApp(t)
+	now := time.Now()
+
+	done := filepath.Join(a.RecordingDir, "recording_20250101_120000_0001.flac")
+	pending := filepath.Join(a.RecordingDir, "recording_20250101_120030_0002.wav")
+	queued := filepath.Join(a.RecordingDir, "recording_20250101_120100_0003.wav")
+	for _, path := range []string{done, pending, queued} {
+		writeTestFile(t, path, 1000, now)
+	}
+	os.WriteFile(filepath.Join(a.RecordingDir, transcribedManifest),
+		[]byte("recording_20250101_120000_0001\nrecording_20250101_120100_0003\n"), 0644)
+	// 文字起こし済みでも処理待ちの一覧にあるものは削除しない
+	a.PendingFiles = []Segment{{Path: queued}}
+
+	if _, err := a.ApplyRetention(RetentionPolicy{DeleteTranscribed: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(done); !os.IsNotExist(err) {
+		t.Errorf("文字起こし済みの録音が削除されていません: %s", done)
+	}
+	for _, path := range []string{pending, queued} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("削除されました: %s", path)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"whisper_local_faster_whsiper_go/internal/audio"
+)
+
+// Segmentは処理待ちの音声セグメント
+// 開始・終了位置はセッション開始からのフレーム数で、一時停止や欠落した区間も含めて数える
+type Segment struct {
+	Path       string     // 音声ファイル（チャンネル別保存時は元のファイル名）
+	SessionID  string     // 録音セッションのID
+	Sequence   int        // セッション内の通し番号（1から）
+	Start      int64      // セッション開始からの開始位置（フレーム）
+	End        int64      // セッション開始からの終了位置（フレーム）
+	SampleRate int        // 開始・終了位置のサンプリングレート（0の場合は位置が不明）
+	Dropped    int64      // 録音中に欠落したフレーム数
+	Clipped    int64      // 保存時に飽和させたサンプル数
+	Bookmarks  []Bookmark // このセグメント内で記録したブックマーク
+}
+
+// セッション内の位置が分かっているか（取り込んだファイルや復旧した録音は不明）
+func (s Segment) HasTimeline() bool {
+	return s.SampleRate > 0
+}
+
+// セッション開始からの開始時刻
+func (s Segment) StartOffset() time.Duration {
+	return framesToDuration(s.Start, s.SampleRate)
+}
+
+// セッション開始からの終了時刻
+func (s Segment) EndOffset() time.Duration {
+	return framesToDuration(s.End, s.SampleRate)
+}
+
+// 見出しなどに使う表示名（例: #12 00:12:30–00:13:00）
+func (s Segment) Label() string {
+	if !s.HasTimeline() {
+		return fmt.Sprintf("#%d %s", s.Sequence, filepath.Base(s.Path))
+	}
+	return fmt.Sprintf("#%d %s–%s", s.Sequence, FormatOffset(s.StartOffset()), FormatOffset(s.EndOffset()))
+}
+
+// セッション開始からの時刻を HH:MM:SS で表示
+func FormatOffset(d time.Duration) string {
+	seconds := int64(d / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// フレーム数を時間に変換
+func framesToDuration(frames int64, sampleRate int) time.Duration {
+	if sampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(frames * int64(time.Second) / int64(sampleRate))
+}
+
+// 録音されずに経過したフレームをセッションの時間軸に加える（一時停止中・デバイス切断中など）
+func (app *App) skipTimeline(frames int64) {
+	app.Mutex.Lock()
+	app.timelineFrames += frames
+	app.capturedFrames += frames
+	app.Mutex.Unlock()
+}
+
+// 現在のセッション開始からの時刻
+func (app *App) SessionOffset() time.Duration {
+	app.Mutex.Lock()
+	defer app.Mutex.Unlock()
+	return framesToDuration(app.timelineFrames, app.SampleRate)
+}
+
+// 次のセグメントを作成し、セッションの時間軸を進める（呼び出し側でロックを保持すること）
+func (app *App) nextSegmentLocked(frames int64) Segment {
+	app.segmentSeq++
+	segment := Segment{
+		SessionID:  app.SessionID,
+		Sequence:   app.segmentSeq,
+		Start:      app.timelineFrames,
+		End:        app.timelineFrames + frames,
+		SampleRate: app.SampleRate,
+	}
+	app.timelineFrames = segment.End
+	return segment
+}
+
+// 処理待ちリストにセグメントを追加（呼び出し側でロックを保持すること）
+func (app *App) queueSegmentLocked(segment Segment) {
+	app.PendingFiles = append(app.PendingFiles, segment)
+	app.lastQueued = segment.Path
+}
+
+// 既存のファイルを処理待ちリストに追加（呼び出し側でロックを保持すること）
+// ファイルにセッションのメタデータがあれば元のセッションでの位置を復元する
+func (app *App) queueFilesLocked(paths []string) {
+	for _, path := range paths {
+		segment, ok := SegmentFromFile(path)
+		if !ok {
+			app.segmentSeq++
+			segment = Segment{Path: path, SessionID: app.SessionID, Sequence: app.segmentSeq}
+		}
+		app.queueSegmentLocked(segment)
+	}
+}
+
+// SegmentFromFileはWAV・FLACに埋め込まれたメタデータからセグメントの情報を復元する
+// チャンネル別に保存したセグメントは最初のチャンネルのファイルから読み取る
+// メタデータがない場合はfalseを返す
+func SegmentFromFile(path string) (Segment, bool) {
+	infoPath := path
+	if _, err := os.Stat(path); err != nil {
+		infoPath = audio.ChannelFilePath(path, 0)
+	}
+	format, err := audio.ReadAudioInfo(infoPath)
+	if err != nil || format.Metadata == nil {
+		return Segment{Path: path}, false
+	}
+
+	metadata := format.Metadata
+	start := int64(metadata.Offset) * int64(format.SampleRate) / int64(time.Second)
+	segment := Segment{
+		Path:       path,
+		SessionID:  metadata.SessionID,
+		Sequence:   metadata.Sequence,
+		Start:      start,
+		End:        start + int64(format.Frames),
+		SampleRate: format.SampleRate,
+	}
+	for _, marker := range metadata.Bookmarks {
+		segment.Bookmarks = append(segment.Bookmarks, Bookmark{Offset: marker.Offset, Label: marker.Label})
+	}
+	return segment, true
+}
@@ -0,0 +1,291 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"whisper_local_faster_whsiper_go/internal/audio"
+)
+
+// StitchOptionsはセッションのセグメントを1つのファイルにつなげる設定
+type StitchOptions struct {
+	SessionID string           // つなげるセッション（空の場合はファイルに含まれる唯一のセッション）
+	Output    string           // 出力先（拡張子が.flacの場合はFLAC、それ以外はWAV）
+	Format    audio.WavOptions // 出力するサンプル形式（FLACは16bitまたは24bit）
+}
+
+// StitchResultはつなげた結果
+type StitchResult struct {
+	SessionID   string        // セッションID
+	Output      string        // 出力したファイル
+	Segments    int           // つなげたセグメント数
+	Skipped     []string      // メタデータがないため除外したファイル
+	Gaps        int           // 無音で埋めた区間の数
+	GapDuration time.Duration // 無音で埋めた時間の合計
+	Duration    time.Duration // 出力したファイルの長さ
+	Markers     int           // 書き込んだマーカーの数
+	Clipped     int64         // 飽和させたサンプル数
+}
+
+// つなげる区間（無音またはセグメント）
+type stitchPart struct {
+	segment *Segment // nilの場合は無音
+	frames  int64    // 長さ（フレーム）
+}
+
+// StitchSessionはセッションのセグメントを通し番号の順に1つのWAVまたはFLACにつなげる
+// 一時停止・欠落・無音でスキップした区間は無音で埋め、出力ファイルの位置がセッション開始からの
+// 経過時刻と一致するようにする。セグメントの境界とブックマークにはマークダウンの見出しと
+// 同じラベルのマーカー（cue/labl）を付ける
+func StitchSession(paths []string, options StitchOptions) (StitchResult, error) {
+	var result StitchResult
+
+	files, err := collectAudioFiles(paths)
+	if err != nil {
+		return result, err
+	}
+
+	// メタデータからセグメントを復元してセッションごとに分ける
+	sessions := make(map[string][]Segment)
+	for _, path := range files {
+		segment, ok := SegmentFromFile(path)
+		if !ok {
+			result.Skipped = append(result.Skipped, path)
+			continue
+		}
+		sessions[segment.SessionID] = append(sessions[segment.SessionID], segment)
+	}
+	if len(sessions) == 0 {
+		return result, fmt.Errorf("セッションのメタデータを持つ録音ファイルがありません")
+	}
+
+	sessionID := options.SessionID
+	if sessionID == "" {
+		if len(sessions) > 1 {
+			ids := make([]string, 0, len(sessions))
+			for id := range sessions {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			return result, fmt.Errorf("複数のセッションが含まれています。-session で指定してください: %s", strings.Join(ids, ", "))
+		}
+		for id := range sessions {
+			sessionID = id
+		}
+	}
+	segments := sessions[sessionID]
+	if len(segments) == 0 {
+		return result, fmt.Errorf("セッション %s の録音ファイルが見つかりません", sessionID)
+	}
+	result.SessionID = sessionID
+
+	// 通し番号順に並べ、同じセグメントのWAVとFLACが両方ある場合は先に見つかった方を使う
+	sort.SliceStable(segments, func(i, j int) bool { return segments[i].Sequence < segments[j].Sequence })
+	unique := segments[:0]
+	for i, segment := range segments {
+		if i > 0 && segment.Sequence == unique[len(unique)-1].Sequence {
+			continue
+		}
+		unique = append(unique, segment)
+	}
+	segments = unique
+
+	// 出力の形式は最初のセグメントに合わせる
+	first, err := readSegmentInfo(segments[0].Path)
+	if err != nil {
+		return result, err
+	}
+	rate, channels := first.SampleRate, first.Channels
+
+	// 配置を決めてマーカーを作成（位置はセッション開始からのフレーム数）
+	var parts []stitchPart
+	var cues []audio.CuePoint
+	var position int64
+	for i := range segments {
+		segment := &segments[i]
+		format, err := readSegmentInfo(segment.Path)
+		if err != nil {
+			return result, err
+		}
+		if format.Channels != channels {
+			return result, fmt.Errorf("チャンネル数が異なるセグメントはつなげられません: %s（%dch、最初のセグメントは%dch）",
+				segment.Path, format.Channels, channels)
+		}
+
+		start := int64(segment.StartOffset()) * int64(rate) / int64(time.Second)
+		if start > position {
+			// 録音されていない区間は無音で埋める
+			gapStart, gapEnd := framesToDuration(position, rate), framesToDuration(start, rate)
+			parts = append(parts, stitchPart{frames: start - position})
+			cues = append(cues, audio.CuePoint{Frame: position,
+				Label: fmt.Sprintf("%s–%s（録音なし）", FormatOffset(gapStart), FormatOffset(gapEnd))})
+			result.Gaps++
+			result.GapDuration += gapEnd - gapStart
+		} else {
+			start = position
+		}
+
+		frames := int64(format.Frames) * int64(rate) / int64(format.SampleRate)
+		parts = append(parts, stitchPart{segment: segment, frames: frames})
+		cues = append(cues, audio.CuePoint{Frame: start, Label: segment.Label()})
+		for _, bookmark := range segment.Bookmarks {
+			frame := int64(bookmark.Offset) * int64(rate) / int64(time.Second)
+			frame = min(max(frame, start), start+frames)
+			cues = append(cues, audio.CuePoint{Frame: frame, Label: "★ " + bookmark.String()})
+		}
+		position = start + frames
+	}
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].Frame < cues[j].Frame })
+
+	// 出力先
+	output := options.Output
+	if output == "" {
+		output = fmt.Sprintf("session_%s.wav", sessionID)
+	}
+	if dir := filepath.Dir(output); dir != "." {
+		os.MkdirAll(dir, 0755)
+	}
+	metadata := &audio.WavMetadata{SessionID: sessionID, Device: first.Metadata.Device}
+	if !first.Metadata.CaptureTime.IsZero() {
+		metadata.CaptureTime = first.Metadata.CaptureTime.Add(-first.Metadata.Offset)
+	}
+
+	if audio.IsFlac(output) {
+		// FLACはMD5を先頭に書くため全体をメモリに読み込んでから書き込む
+		var buffer [][]float32
+		err = writeStitchParts(parts, rate, channels, func(samples []float32) error {
+			buffer = append(buffer, samples)
+			return nil
+		})
+		if err == nil {
+			result.Clipped, err = audio.WriteFlacFile(output, buffer, rate, channels, options.Format.Format,
+				audio.FlacOptions{Metadata: metadata, Cues: cues})
+		}
+	} else {
+		result.Clipped, err = writeStitchedWav(output, parts, position, rate, channels, options.Format, metadata, cues)
+	}
+	if err != nil {
+		os.Remove(output)
+		return result, err
+	}
+
+	result.Output = output
+	result.Segments = len(segments)
+	result.Duration = framesToDuration(position, rate)
+	result.Markers = len(cues)
+	return result, nil
+}
+
+// つなげた音声をWAVとして書き込む（セグメントを1つずつ読み込んで追記する）
+func writeStitchedWav(path string, parts []stitchPart, frames int64, rate, channels int,
+	options audio.WavOptions, metadata *audio.WavMetadata, cues []audio.CuePoint) (int64, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("WAVファイルを作成できませんでした: %v", err)
+	}
+
+	options.Metadata = metadata
+	options.Cues = cues
+	encoder := audio.NewWavEncoder(file, options)
+	err = encoder.WriteHeader(int(frames)*channels, rate, channels)
+	if err == nil {
+		err = writeStitchParts(parts, rate, channels, encoder.WriteSamples)
+	}
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("WAVファイルを保存できませんでした: %v", closeErr)
+	}
+	return encoder.Clipped(), err
+}
+
+// 区間を順に読み込み、インターリーブされたサンプルとしてwriteに渡す
+// セグメントはサンプリングレートを揃え、配置した長さに合わせて切り詰め・無音で補う
+func writeStitchParts(parts []stitchPart, rate, channels int, write func([]float32) error) error {
+	silence := make([]float32, rate*channels) // 1秒分の無音（使い回す）
+	writeSilence := func(frames int64) error {
+		for frames > 0 {
+			n := min(frames, int64(rate))
+			if err := write(silence[:n*int64(channels)]); err != nil {
+				return err
+			}
+			frames -= n
+		}
+		return nil
+	}
+
+	for _, part := range parts {
+		if part.segment == nil {
+			if err := writeSilence(part.frames); err != nil {
+				return err
+			}
+			continue
+		}
+
+		samples, format, err := readSegmentAudio(part.segment.Path)
+		if err != nil {
+			return err
+		}
+		if format.SampleRate != rate {
+			perChannel := audio.Deinterleave(samples, channels)
+			for c := range perChannel {
+				perChannel[c] = audio.Resample(perChannel[c], format.SampleRate, rate)
+			}
+			samples = audio.Interleave(perChannel)
+		}
+
+		length := int64(len(samples) / channels)
+		if length > part.frames {
+			samples = samples[:part.frames*int64(channels)]
+			length = part.frames
+		}
+		if err := write(samples); err != nil {
+			return err
+		}
+		if err := writeSilence(part.frames - length); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// セグメントの形式とメタデータを読み込む（チャンネル別に保存した場合はファイル数をチャンネル数とする）
+func readSegmentInfo(path string) (audio.WavFormat, error) {
+	if _, err := os.Stat(path); err == nil {
+		return audio.ReadAudioInfo(path)
+	}
+
+	channelPaths, err := audio.ChannelFiles(path)
+	if err != nil {
+		return audio.WavFormat{}, err
+	}
+	format, err := audio.ReadAudioInfo(channelPaths[0])
+	format.Channels = len(channelPaths)
+	return format, err
+}
+
+// セグメントの音声を読み込む（チャンネル別に保存した場合はインターリーブし直す）
+func readSegmentAudio(path string) ([]float32, audio.WavFormat, error) {
+	if _, err := os.Stat(path); err == nil {
+		return audio.ReadAudio(path)
+	}
+
+	channelPaths, err := audio.ChannelFiles(path)
+	if err != nil {
+		return nil, audio.WavFormat{}, err
+	}
+	perChannel := make([][]float32, len(channelPaths))
+	var format audio.WavFormat
+	for c, channelPath := range channelPaths {
+		samples, channelFormat, err := audio.ReadAudio(channelPath)
+		if err != nil {
+			return nil, format, err
+		}
+		perChannel[c] = samples
+		format = channelFormat
+	}
+	format.Channels = len(channelPaths)
+	return audio.Interleave(perChannel), format, nil
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"whisper_local_faster_whsiper_go/internal/audio"
+)
+
+// 一定の値が続くセグメントをメタデータ付きで保存する
+func writeStitchSegment(t *testing.T, dir string, sequence int, offset time.Duration, value float32, bookmarks ...audio.Marker) {
+	t.Helper()
+	samples := make([]float32, 16000)
+	for i := range samples {
+		samples[i] = value
+	}
+	metadata := &audio.WavMetadata{SessionID: "20250101_120000", Sequence: sequence, Offset: offset, Bookmarks: bookmarks}
+	path := filepath.Join(dir, fmt.Sprintf("recording_20250101_120000_%04d.wav", sequence))
+	if _, err := audio.WriteWavFile(path, [][]float32{samples}, 16000, 1, audio.WavOptions{Metadata: metadata}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStitchSession(t *testing.T) {
+	dir := t.TempDir()
+	recordings := filepath.Join(dir, "recordings")
+	os.MkdirAll(recordings, 0755)
+
+	// 0秒・3秒・6秒から1秒ずつ（1〜3秒は一時停止、4〜6秒は無音でスキップしたセグメント）
+	writeStitchSegment(t, recordings, 1, 0, 0.25, audio.Marker{Offset: 500 * time.Millisecond, Label: "結論"})
+	writeStitchSegment(t, recordings, 2, 3*time.Second, 0.5)
+	writeStitchSegment(t, recordings, 3, 6*time.Second, -0.5)
+	// メタデータのないファイルは除外する
+	if _, err := audio.WriteWavFile(filepath.Join(recordings, "other.wav"), [][]float32{make([]float32, 1600)}, 16000, 1, audio.WavOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	wantCues := []int64{0, 8000, 16000, 48000, 64000, 96000}
+	for _, name := range []string{"session.wav", "session.flac"} {
+		t.Run(name, func(t *testing.T) {
+			output := filepath.Join(dir, name)
+			result, err := StitchSession([]string{recordings}, StitchOptions{Output: output, Format: audio.WavOptions{Format: audio.PCM16}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Segments != 3 || result.Gaps != 2 || result.GapDuration != 4*time.Second || result.Duration != 7*time.Second || len(result.Skipped) != 1 {
+				t.Errorf("結果 %+v", result)
+			}
+
+			samples, format, err := audio.ReadAudio(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format.Frames != 7*16000 || format.SampleRate != 16000 || format.Channels != 1 {
+				t.Fatalf("形式 %s・%d フレーム（期待値 112000）", format, format.Frames)
+			}
+
+			// セッション開始からの経過時刻の位置にセグメントがあり、録音していない区間は無音
+			for _, check := range []struct {
+				frame int
+				want  float32
+			}{
+				{100, 0.25}, {15999, 0.25}, {16000, 0}, {47999, 0}, {48000, 0.5}, {80000, 0}, {96000, -0.5}, {111999, -0.5},
+			} {
+				if math.Abs(float64(samples[check.frame]-check.want)) > 1e-3 {
+					t.Errorf("フレーム %d = %v（期待値 %v）", check.frame, samples[check.frame], check.want)
+				}
+			}
+
+			// セグメントの境界・ブックマーク・録音していない区間のマーカー
+			if len(format.Cues) != len(wantCues) {
+				t.Fatalf("マーカー %+v", format.Cues)
+			}
+			for i, cue := range format.Cues {
+				if cue.Frame != wantCues[i] {
+					t.Errorf("マーカー %d の位置 %d（期待値 %d）", i, cue.Frame, wantCues[i])
+				}
+			}
+			if label := format.Cues[1].Label; !strings.HasPrefix(label, "★ ") || !strings.Contains(label, "結論") {
+				t.Errorf("ブックマークのラベル %q", label)
+			}
+			if label := format.Cues[2].Label; label != "00:00:01–00:00:03（録音なし）" {
+				t.Errorf("録音していない区間のラベル %q", label)
+			}
+			if format.Metadata == nil || format.Metadata.SessionID != "20250101_120000" {
+				t.Errorf("メタデータ %+v", format.Metadata)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// チャンネル別ファイルの名前（例: recording_20250101_120000_ch1.wav）
+var channelFileRegex = regexp.MustCompile(`^(.*)_ch(\d+)(\.[^.]+)$`)
+
+// Deinterleaveはインターリーブされたサンプルをチャンネルごとに分ける
+func Deinterleave(samples []float32, channels int) [][]float32 {
+	frames := len(samples) / channels
+	out := make([][]float32, channels)
+	for c := range out {
+		out[c] = make([]float32, frames)
+	}
+	for i := 0; i < frames; i++ {
+		for c := 0; c < channels; c++ {
+			out[c][i] = samples[i*channels+c]
+		}
+	}
+	return out
+}
+
+// Interleaveはチャンネルごとのサンプルをインターリーブする
+// 長さが異なる場合は最短のチャンネルに合わせる
+func Interleave(channels [][]float32) []float32 {
+	if len(channels) == 0 {
+		return nil
+	}
+	frames := len(channels[0])
+	for _, ch := range channels[1:] {
+		if len(ch) < frames {
+			frames = len(ch)
+		}
+	}
+	out := make([]float32, frames*len(channels))
+	for i := 0; i < frames; i++ {
+		for c, ch := range channels {
+			out[i*len(channels)+c] = ch[i]
+		}
+	}
+	return out
+}
+
+// ChannelFilePathはチャンネル別ファイルのパスを返す（chは0始まり）
+func ChannelFilePath(path string, ch int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s_ch%d%s", strings.TrimSuffix(path, ext), ch+1, ext)
+}
+
+// ChannelBasePathはチャンネル別ファイルのパスから元のセグメントのパスを返す
+func ChannelBasePath(path string) (string, bool) {
+	dir, name := filepath.Split(path)
+	m := channelFileRegex.FindStringSubmatch(name)
+	if m == nil {
+		return path, false
+	}
+	return filepath.Join(dir, m[1]+m[3]), true
+}
+
+// SaveAsWavPerChannelはインターリーブされたバッファをチャンネルごとのWAVファイルに保存する
+// 戻り値の整数は全チャンネルで飽和させたサンプル数
+func SaveAsWavPerChannel(path string, audioBuffer [][]float32, sampleRate int, channels int, options WavOptions) ([]string, int64, error) {
+	totalSamples := 0
+	for _, buffer := range audioBuffer {
+		totalSamples += len(buffer)
+	}
+
+	paths := make([]string, 0, channels)
+	var clipped int64
+	for c := 0; c < channels; c++ {
+		chPath := ChannelFilePath(path, c)
+		n, err := writeChannelFile(chPath, audioBuffer, totalSamples/channels, sampleRate, channels, c, options)
+		clipped += n
+		if err != nil {
+			return paths, clipped, err
+		}
+		paths = append(paths, chPath)
+	}
+	return paths, clipped, nil
+}
+
+// インターリーブされたバッファからチャンネルcを取り出してモノラルのWAVファイルに保存
+func writeChannelFile(path string, audioBuffer [][]float32, frames int, sampleRate int, channels int, c int, options WavOptions) (int64, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("WAVファイルを作成できませんでした: %v", err)
+	}
+
+	encoder := NewWavEncoder(file, options)
+	err = encoder.WriteHeader(frames, sampleRate, 1)
+	if err == nil {
+		err = encoder.WriteChannel(audioBuffer, channels, c)
+	}
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("WAVファイルを保存できませんでした: %v", closeErr)
+	}
+	return encoder.Clipped(), err
+}
+
+// ChannelFilesはセグメントのチャンネルごとのモノラル音声ファイルを返す
+// 複数チャンネルのWAVはチャンネル別ファイルに分割し、
+// セグメントのファイルが存在しない場合は既存のチャンネル別ファイルを探す
+func ChannelFiles(path string) ([]string, error) {
+	if _, err := os.Stat(path); err == nil {
+		samples, format, err := ReadWav(path)
+		if err != nil {
+			return nil, err
+		}
+		if format.Channels == 1 {
+			return []string{path}, nil
+		}
+		// whisperに渡すチャンネル別ファイルは16bitで十分
+		paths, _, err := SaveAsWavPerChannel(path, [][]float32{samples}, format.SampleRate, format.Channels, WavOptions{Metadata: format.Metadata})
+		return paths, err
+	}
+
+	matches, err := filepath.Glob(strings.TrimSuffix(path, filepath.Ext(path)) + "_ch*" + filepath.Ext(path))
+	if err != nil || len(matches) == 0 {
+		return nil, fmt.Errorf("オーディオファイルが見つかりません: %s", path)
+	}
+
+	// チャンネル番号順に並べる
+	channelNumber := func(p string) int {
+		m := channelFileRegex.FindStringSubmatch(filepath.Base(p))
+		if m == nil {
+			return 0
+		}
+		n, _ := strconv.Atoi(m[2])
+		return n
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return channelNumber(matches[i]) < channelNumber(matches[j])
+	})
+	return matches, nil
+}
@@ -0,0 +1,154 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// SampleFormatはWAVに書き込むサンプルの形式
+type SampleFormat int
+
+const (
+	PCM16   SampleFormat = iota // 16bit 整数PCM
+	PCM24                       // 24bit 整数PCM
+	Float32                     // 32bit 浮動小数点
+)
+
+// ParseSampleFormatは "16" / "24" / "float" をサンプル形式に変換する
+func ParseSampleFormat(s string) (SampleFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "16", "pcm16", "s16":
+		return PCM16, nil
+	case "24", "pcm24", "s24":
+		return PCM24, nil
+	case "float", "f32", "float32":
+		return Float32, nil
+	}
+	return PCM16, fmt.Errorf("不明なサンプル形式です: %s（16 / 24 / float）", s)
+}
+
+// 1サンプルのビット数
+func (f SampleFormat) BitsPerSample() int {
+	switch f {
+	case PCM24:
+		return 24
+	case Float32:
+		return 32
+	default:
+		return 16
+	}
+}
+
+// WAVのフォーマットタグ
+func (f SampleFormat) formatTag() uint16 {
+	if f == Float32 {
+		return wavFormatFloat
+	}
+	return wavFormatPCM
+}
+
+func (f SampleFormat) String() string {
+	switch f {
+	case PCM24:
+		return "24bit PCM"
+	case Float32:
+		return "32bit float"
+	default:
+		return "16bit PCM"
+	}
+}
+
+// WavOptionsはWAVファイルの書き込み設定
+type WavOptions struct {
+	Format SampleFormat // サンプル形式
+	Dither bool         // 整数PCMへの変換時にTPDFディザーを加える
+
+	Metadata *WavMetadata // LIST/INFOとbextチャンクに書き込むメタデータ（nilの場合は書き込まない）
+	Cues     []CuePoint   // cue/lablチャンクに書き込むマーカー
+}
+
+// sampleEncoderはfloat32のサンプルをWAVのバイト列に変換する
+// 整数PCMでは範囲外の値を飽和させ、その数をClippedに数える
+type sampleEncoder struct {
+	options WavOptions
+	rng     *rand.Rand
+	Clipped int64 // -1.0〜1.0の範囲を超えて飽和させたサンプル数
+}
+
+func newSampleEncoder(options WavOptions) *sampleEncoder {
+	e := &sampleEncoder{options: options}
+	if options.Dither {
+		e.rng = rand.New(rand.NewSource(1))
+	}
+	return e
+}
+
+// 1サンプルのバイト数
+func (e *sampleEncoder) bytesPerSample() int {
+	return e.options.Format.BitsPerSample() / 8
+}
+
+// samplesを変換してdstに追加する
+func (e *sampleEncoder) encode(dst []byte, samples []float32) []byte {
+	size := e.bytesPerSample()
+	start := len(dst)
+	if cap(dst)-start < len(samples)*size {
+		grown := make([]byte, start, start+len(samples)*size)
+		copy(grown, dst)
+		dst = grown
+	}
+	dst = dst[:start+len(samples)*size]
+	out := dst[start:]
+
+	switch e.options.Format {
+	case Float32:
+		// 浮動小数点は範囲外の値もそのまま保存できる（NaNのみ0にする）
+		for i, sample := range samples {
+			if sample != sample {
+				sample = 0
+			}
+			binary.LittleEndian.PutUint32(out[i*4:], math.Float32bits(sample))
+		}
+	case PCM24:
+		for i, sample := range samples {
+			v := e.quantize(sample, 8388607)
+			out[i*3] = byte(v)
+			out[i*3+1] = byte(v >> 8)
+			out[i*3+2] = byte(v >> 16)
+		}
+	default:
+		for i, sample := range samples {
+			binary.LittleEndian.PutUint16(out[i*2:], uint16(int16(e.quantize(sample, 32767))))
+		}
+	}
+	return dst
+}
+
+// -1.0〜1.0のサンプルを±maxValueの整数に変換する
+// 範囲外の値は折り返さずに最大値・最小値に飽和させる
+func (e *sampleEncoder) quantize(sample float32, maxValue float64) int32 {
+	if sample != sample {
+		return 0
+	}
+	if sample > 1 || sample < -1 {
+		e.Clipped++
+	}
+
+	v := float64(sample) * maxValue
+	if e.rng != nil {
+		// TPDFディザー（2つの一様乱数の差、±1LSB）
+		v += e.rng.Float64() - e.rng.Float64()
+	}
+	v = math.Round(v)
+
+	if v > maxValue {
+		return int32(maxValue)
+	}
+	if v < -maxValue-1 {
+		return int32(-maxValue - 1)
+	}
+	return int32(v)
+}
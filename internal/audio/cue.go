@@ -0,0 +1,98 @@
+package audio
+
+import (
+	"encoding/binary"
+	"sort"
+	"strings"
+)
+
+// CuePointはWAVのcue/lablチャンクに書き込むマーカー
+type CuePoint struct {
+	Frame int64  // ファイル先頭からの位置（フレーム）
+	Label string // ラベル
+}
+
+// cueチャンクとラベルを入れたLIST/adtlチャンクを作成
+func cueChunks(points []CuePoint) []byte {
+	if len(points) == 0 {
+		return nil
+	}
+
+	cue := binary.LittleEndian.AppendUint32(nil, uint32(len(points)))
+	adtl := []byte("adtl")
+	for i, point := range points {
+		id := uint32(i + 1)
+		cue = binary.LittleEndian.AppendUint32(cue, id)
+		cue = binary.LittleEndian.AppendUint32(cue, uint32(point.Frame)) // 再生順の位置
+		cue = append(cue, "data"...)
+		cue = binary.LittleEndian.AppendUint32(cue, 0) // チャンクの開始位置
+		cue = binary.LittleEndian.AppendUint32(cue, 0) // ブロックの開始位置
+		cue = binary.LittleEndian.AppendUint32(cue, uint32(point.Frame))
+
+		label := binary.LittleEndian.AppendUint32(nil, id)
+		label = append(label, point.Label...)
+		label = append(label, 0)
+		adtl = appendChunk(adtl, "labl", label)
+	}
+
+	out := appendChunk(nil, "cue ", cue)
+	return appendChunk(out, "LIST", adtl)
+}
+
+// cueチャンクからマーカーの位置を読み取る（IDごと）
+func parseCueChunk(body []byte, labels map[uint32]*CuePoint) {
+	if len(body) < 4 {
+		return
+	}
+	count := int(binary.LittleEndian.Uint32(body))
+	for i := 0; i < count && 4+(i+1)*24 <= len(body); i++ {
+		point := body[4+i*24:]
+		id := binary.LittleEndian.Uint32(point[0:4])
+		if labels[id] == nil {
+			labels[id] = &CuePoint{}
+		}
+		labels[id].Frame = int64(binary.LittleEndian.Uint32(point[20:24]))
+	}
+}
+
+// LIST/adtlチャンクからマーカーのラベルを読み取る
+func parseAdtlChunk(body []byte, labels map[uint32]*CuePoint) {
+	if len(body) < 4 || string(body[0:4]) != "adtl" {
+		return
+	}
+	for pos := 4; pos+8 <= len(body); {
+		id := string(body[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(body[pos+4 : pos+8]))
+		pos += 8
+		if size > len(body)-pos {
+			break
+		}
+		if id == "labl" && size >= 4 {
+			cueID := binary.LittleEndian.Uint32(body[pos : pos+4])
+			if labels[cueID] == nil {
+				labels[cueID] = &CuePoint{}
+			}
+			labels[cueID].Label = strings.TrimRight(string(body[pos+4:pos+size]), "\x00")
+		}
+		pos += size + size%2
+	}
+}
+
+// IDごとのマーカーを位置順に並べる
+func sortedCues(labels map[uint32]*CuePoint) []CuePoint {
+	if len(labels) == 0 {
+		return nil
+	}
+	ids := make([]uint32, 0, len(labels))
+	for id := range labels {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	cues := make([]CuePoint, 0, len(labels))
+	for _, id := range ids {
+		cues = append(cues, *labels[id])
+	}
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].Frame < cues[j].Frame })
+	return cues
+}
@@ -0,0 +1,254 @@
+package device
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/gordonklaus/portaudio"
+
+	"whisper_local_faster_whsiper_go/internal/audio"
+)
+
+// コールバックから読み出し側へ渡すリングバッファの長さ（秒）
+const ringBufferSeconds = 10
+
+// デバイス復帰を確認する間隔
+const recoverPollInterval = time.Second
+
+// PortAudioSourceはPortAudioの入力デバイスから録音する入力源
+// コールバックでリングバッファに書き込み、Readで読み出す
+type PortAudioSource struct {
+	Device          audio.DeviceInfo // 入力デバイス
+	NumCh           int              // チャンネル数
+	Rate            int              // サンプリングレート
+	FramesPerBuffer int              // 1回の読み取りフレーム数
+	Fallbacks       []string         // 元のデバイスが見つからない場合に使うデバイス名
+
+	stream  *portaudio.Stream
+	ring    *audio.RingBuffer
+	ready   chan struct{} // データ到着の通知
+	dropped atomic.Int64  // 欠落したフレーム数
+
+	// コールバック内でのみ使用
+	nextAdcTime time.Duration
+	started     bool
+}
+
+// InitializeはPortAudioを初期化する（デバイスから録音する場合のみ呼び出し、終了時にTerminateを呼ぶ）
+func Initialize() error {
+	if err := portaudio.Initialize(); err != nil {
+		return fmt.Errorf("PortAudio初期化エラー: %v", err)
+	}
+	return nil
+}
+
+// TerminateはPortAudioを終了する
+func Terminate() {
+	portaudio.Terminate()
+}
+
+// InputDevicesは入力に対応したデバイスの一覧を返す
+func InputDevices() ([]audio.DeviceInfo, error) {
+	devices, err := portaudio.Devices()
+	if err != nil {
+		return nil, fmt.Errorf("デバイス一覧の取得エラー: %v", err)
+	}
+	inputs := make([]audio.DeviceInfo, 0)
+	for _, device := range devices {
+		if device.MaxInputChannels > 0 {
+			inputs = append(inputs, deviceInfo(device))
+		}
+	}
+	return inputs, nil
+}
+
+// PortAudioのデバイス情報を変換
+func deviceInfo(device *portaudio.DeviceInfo) audio.DeviceInfo {
+	return audio.DeviceInfo{
+		Index:             device.Index,
+		Name:              device.Name,
+		MaxInputChannels:  device.MaxInputChannels,
+		DefaultSampleRate: device.DefaultSampleRate,
+	}
+}
+
+// 新しいPortAudio入力源を作成
+func NewPortAudioSource(device audio.DeviceInfo, channels, sampleRate, framesPerBuffer int) *PortAudioSource {
+	return &PortAudioSource{
+		Device:          device,
+		NumCh:           channels,
+		Rate:            sampleRate,
+		FramesPerBuffer: framesPerBuffer,
+	}
+}
+
+func (s *PortAudioSource) Open() error {
+	if s.Device.Name == "" {
+		return fmt.Errorf("録音デバイスが指定されていません")
+	}
+	device, err := findDevice(s.Device)
+	if err != nil {
+		return err
+	}
+
+	s.ring = audio.NewRingBuffer(s.Rate*s.NumCh*ringBufferSeconds, s.NumCh)
+	s.ready = make(chan struct{}, 1)
+	s.started = false
+
+	params := portaudio.HighLatencyParameters(device, nil)
+	params.Input.Channels = s.NumCh
+	params.SampleRate = float64(s.Rate)
+	params.FramesPerBuffer = s.FramesPerBuffer
+
+	// デバイスが要求した形式に対応しているか事前に確認
+	if err := portaudio.IsFormatSupported(params, s.callback); err != nil {
+		return fmt.Errorf("デバイス %s は %dチャンネル/%d Hz での録音に対応していません: %v",
+			s.Device.Name, s.NumCh, s.Rate, err)
+	}
+
+	stream, err := portaudio.OpenStream(params, s.callback)
+	if err != nil {
+		return fmt.Errorf("デバイス %s のストリームを開けませんでした: %v", s.Device.Name, err)
+	}
+
+	if err := stream.Start(); err != nil {
+		stream.Close()
+		return fmt.Errorf("ストリームを開始できませんでした: %v", err)
+	}
+
+	s.stream = stream
+	return nil
+}
+
+// PortAudioのコールバック（ブロックやメモリ確保をしないこと）
+func (s *PortAudioSource) callback(in []float32, timeInfo portaudio.StreamCallbackTimeInfo, flags portaudio.StreamCallbackFlags) {
+	frames := len(in) / s.NumCh
+
+	// PortAudio側で入力が溢れた場合はADC時刻の飛びから欠落フレーム数を求める
+	if s.started && flags&portaudio.InputOverflow != 0 {
+		if gap := timeInfo.InputBufferAdcTime - s.nextAdcTime; gap > 0 {
+			s.dropped.Add(int64(gap.Seconds() * float64(s.Rate)))
+		}
+	}
+	s.nextAdcTime = timeInfo.InputBufferAdcTime + time.Duration(float64(frames)/float64(s.Rate)*float64(time.Second))
+	s.started = true
+
+	// 読み出しが追いつかずリングバッファが満杯の場合は書き込めなかったフレームを欠落として数える
+	written := s.ring.Write(in[:frames*s.NumCh]) / s.NumCh
+	if written < frames {
+		s.dropped.Add(int64(frames - written))
+	}
+
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Readはbufが埋まるか一定時間経過するまで待つ
+// データが全く届かない場合はaudio.ErrNoInputを返す
+func (s *PortAudioSource) Read(buf []float32) (int, error) {
+	if s.stream == nil {
+		return 0, fmt.Errorf("ストリームが開かれていません")
+	}
+
+	// フレーム単位で読み出す
+	want := len(buf) - len(buf)%s.NumCh
+	timeout := time.NewTimer(time.Second)
+	defer timeout.Stop()
+
+	for s.ring.Available() < want {
+		select {
+		case <-s.ready:
+		case <-timeout.C:
+			want = s.ring.Available() - s.ring.Available()%s.NumCh
+			if want == 0 {
+				return 0, audio.ErrNoInput
+			}
+		}
+	}
+
+	n := s.ring.Read(buf[:want])
+	return n / s.NumCh, nil
+}
+
+// DroppedFramesはこれまでに欠落したフレーム数の累計を返す
+func (s *PortAudioSource) DroppedFrames() int64 {
+	return s.dropped.Load()
+}
+
+// Recoverはストリームを閉じ、同じ名前のデバイスか代替デバイスが使えるようになるまで待って開き直す
+// デバイス一覧を更新するためPortAudioを再初期化する
+func (s *PortAudioSource) Recover(ctx context.Context) error {
+	s.Close()
+
+	candidates := append([]string{s.Device.Name}, s.Fallbacks...)
+	for {
+		portaudio.Terminate()
+		if err := portaudio.Initialize(); err == nil {
+			if devices, err := portaudio.Devices(); err == nil {
+				for _, name := range candidates {
+					device := findInputDevice(devices, name)
+					if device == nil || device.MaxInputChannels < s.NumCh {
+						continue
+					}
+					s.Device = deviceInfo(device)
+					if err := s.Open(); err == nil {
+						return nil
+					}
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(recoverPollInterval):
+		}
+	}
+}
+
+// 一覧を取得した時点のデバイスを探す（番号が変わっている場合は名前で探す）
+func findDevice(info audio.DeviceInfo) (*portaudio.DeviceInfo, error) {
+	devices, err := portaudio.Devices()
+	if err != nil {
+		return nil, fmt.Errorf("デバイス一覧の取得エラー: %v", err)
+	}
+	if info.Index >= 0 && info.Index < len(devices) && devices[info.Index].Name == info.Name {
+		return devices[info.Index], nil
+	}
+	if device := findInputDevice(devices, info.Name); device != nil {
+		return device, nil
+	}
+	return nil, fmt.Errorf("録音デバイスが見つかりません: %s", info.Name)
+}
+
+// 名前が一致する入力デバイスを探す
+func findInputDevice(devices []*portaudio.DeviceInfo, name string) *portaudio.DeviceInfo {
+	for _, device := range devices {
+		if device.Name == name && device.MaxInputChannels > 0 {
+			return device
+		}
+	}
+	return nil
+}
+
+// Nameは使用中のデバイス名を返す
+func (s *PortAudioSource) Name() string {
+	return s.Device.Name
+}
+
+func (s *PortAudioSource) SampleRate() int { return s.Rate }
+func (s *PortAudioSource) Channels() int   { return s.NumCh }
+
+func (s *PortAudioSource) Close() error {
+	if s.stream == nil {
+		return nil
+	}
+	s.stream.Stop()
+	err := s.stream.Close()
+	s.stream = nil
+	return err
+}
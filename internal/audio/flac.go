@@ -0,0 +1,524 @@
+package audio
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"strings"
+	"time"
+)
+
+// FLACの1ブロックのフレーム数
+const flacBlockSize = 4096
+
+// 固定予測の最大次数
+const flacMaxFixedOrder = 4
+
+// Riceパーティションの最大分割次数
+const flacMaxPartitionOrder = 8
+
+// 4bitで書けるRiceパラメータの最大値（15はエスケープ符号）
+const flacMaxRiceParam = 14
+
+// SaveAsFlacはインターリーブされたバッファを16bitのFLACファイルとして保存する
+func SaveAsFlac(path string, audioBuffer [][]float32, sampleRate int, channels int) error {
+	_, err := WriteFlacFile(path, audioBuffer, sampleRate, channels, PCM16, FlacOptions{})
+	return err
+}
+
+// WriteFlacFileはインターリーブされたバッファを16bitまたは24bitのFLACファイルとして保存する
+// -1.0〜1.0を超えるサンプルは飽和させ、その数を返す（浮動小数点は指定できない）
+func WriteFlacFile(path string, audioBuffer [][]float32, sampleRate int, channels int, format SampleFormat, options FlacOptions) (int64, error) {
+	if len(audioBuffer) == 0 {
+		return 0, fmt.Errorf("保存するオーディオバッファが空です")
+	}
+	if format == Float32 {
+		return 0, fmt.Errorf("FLACは浮動小数点のサンプルに対応していません")
+	}
+
+	maxValue := float64(int32(1)<<(format.BitsPerSample()-1) - 1)
+	encoder := newSampleEncoder(WavOptions{Format: format})
+	combined := Concat(audioBuffer)
+	samples := make([]int32, len(combined)-len(combined)%channels)
+	for i := range samples {
+		samples[i] = encoder.quantize(combined[i], maxValue)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("FLACファイルを作成できませんでした: %v", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if err := EncodeFlac(writer, samples, sampleRate, channels, format.BitsPerSample(), options); err != nil {
+		return encoder.Clipped, err
+	}
+	if err := writer.Flush(); err != nil {
+		return encoder.Clipped, fmt.Errorf("FLACの書き込みエラー: %v", err)
+	}
+	return encoder.Clipped, nil
+}
+
+// ErrFlacLossyはWAVをFLACに変換すると値が変わってしまう場合のエラー
+var ErrFlacLossy = errors.New("FLACで可逆に保存できない形式です")
+
+// ConvertToFlacはWAVファイルを同じ形式のFLACファイルに変換する
+// 8〜24bitの整数PCMだけを変換し、セッションのメタデータはVORBIS_COMMENTに移す
+// 浮動小数点や32bitのWAVは値が変わってしまうため、変換せずErrFlacLossyを返す
+func ConvertToFlac(wavPath, flacPath string) error {
+	samples, format, err := ReadWav(wavPath)
+	if err != nil {
+		return err
+	}
+	if format.Float || format.BitsPerSample > 24 {
+		return fmt.Errorf("%w: %s", ErrFlacLossy, format)
+	}
+
+	// 8〜24bitの整数はfloat32で正確に表せるため、元の値に戻る
+	bitsPerSample := format.BitsPerSample
+	scale := float32(int32(1) << (bitsPerSample - 1))
+	pcm := make([]int32, len(samples))
+	for i, sample := range samples {
+		pcm[i] = int32(sample * scale)
+	}
+
+	file, err := os.Create(flacPath)
+	if err != nil {
+		return fmt.Errorf("FLACファイルを作成できませんでした: %v", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if err := EncodeFlac(writer, pcm, format.SampleRate, format.Channels, bitsPerSample, FlacOptions{Metadata: format.Metadata}); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// FlacOptionsはFLACに書き込むメタデータ
+type FlacOptions struct {
+	Metadata *WavMetadata // VORBIS_COMMENTに書き込むセッションのメタデータ（nilの場合は書き込まない）
+	Cues     []CuePoint   // RIFFのcue/lablチャンク（APPLICATIONブロック）とCHAPTERコメントに書き込むマーカー
+}
+
+// FLACのメタデータブロック
+type flacMetadataBlock struct {
+	blockType byte
+	body      []byte
+}
+
+// EncodeFlacはインターリーブされた整数サンプルをFLACとして書き込む
+// 各チャンネルを固定予測とRice符号で可逆圧縮する（bitsPerSampleは8〜24）
+func EncodeFlac(w io.Writer, samples []int32, sampleRate, channels, bitsPerSample int, options FlacOptions) error {
+	if channels < 1 || channels > 8 {
+		return fmt.Errorf("FLACは1〜8チャンネルまでしか対応していません: %d", channels)
+	}
+	if bitsPerSample < 8 || bitsPerSample > 24 {
+		return fmt.Errorf("未対応のビット深度です: %d", bitsPerSample)
+	}
+	if sampleRate < 1 || sampleRate >= 1<<20 {
+		return fmt.Errorf("未対応のサンプリングレートです: %d", sampleRate)
+	}
+
+	frames := len(samples) / channels
+
+	// ストリーム情報（MD5はサンプルをリトルエンディアンで並べたもの）とメタデータ
+	blocks := []flacMetadataBlock{{0, flacStreamInfo(samples[:frames*channels], sampleRate, channels, bitsPerSample)}}
+	if options.Metadata != nil || len(options.Cues) > 0 {
+		blocks = append(blocks, flacMetadataBlock{4, flacVorbisComment(options.Metadata, options.Cues, sampleRate)})
+	}
+	if len(options.Cues) > 0 {
+		// RIFFのcue/lablチャンクをそのままAPPLICATIONブロック（ID: riff）に入れる
+		blocks = append(blocks, flacMetadataBlock{2, append([]byte("riff"), cueChunks(options.Cues)...)})
+	}
+
+	header := []byte("fLaC")
+	for i, block := range blocks {
+		blockType := block.blockType
+		if i == len(blocks)-1 {
+			blockType |= 0x80 // 最後のメタデータブロック
+		}
+		size := len(block.body)
+		header = append(header, blockType, byte(size>>16), byte(size>>8), byte(size))
+		header = append(header, block.body...)
+	}
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("FLACの書き込みエラー: %v", err)
+	}
+
+	// ブロックごとにフレームを書き込む
+	block := make([][]int32, channels)
+	for c := range block {
+		block[c] = make([]int32, flacBlockSize)
+	}
+	var frame bitWriter
+	for start, number := 0, uint64(0); start < frames; start, number = start+flacBlockSize, number+1 {
+		size := frames - start
+		if size > flacBlockSize {
+			size = flacBlockSize
+		}
+		for c := range block {
+			block[c] = block[c][:size]
+			for i := 0; i < size; i++ {
+				block[c][i] = samples[(start+i)*channels+c]
+			}
+		}
+
+		frame.reset()
+		writeFlacFrame(&frame, block, number, bitsPerSample)
+		if _, err := w.Write(frame.bytes()); err != nil {
+			return fmt.Errorf("FLACの書き込みエラー: %v", err)
+		}
+	}
+	return nil
+}
+
+// STREAMINFOメタデータブロックの内容
+func flacStreamInfo(samples []int32, sampleRate, channels, bitsPerSample int) []byte {
+	var b bitWriter
+	b.writeBits(flacBlockSize, 16) // 最小ブロックサイズ
+	b.writeBits(flacBlockSize, 16) // 最大ブロックサイズ
+	b.writeBits(0, 24)             // 最小フレームサイズ（不明）
+	b.writeBits(0, 24)             // 最大フレームサイズ（不明）
+	b.writeBits(uint64(sampleRate), 20)
+	b.writeBits(uint64(channels-1), 3)
+	b.writeBits(uint64(bitsPerSample-1), 5)
+	b.writeBits(uint64(len(samples)/channels), 36)
+
+	hash := md5.New()
+	bytesPerSample := (bitsPerSample + 7) / 8
+	buf := make([]byte, 4)
+	for _, s := range samples {
+		binary.LittleEndian.PutUint32(buf, uint32(s))
+		hash.Write(buf[:bytesPerSample])
+	}
+	for _, v := range hash.Sum(nil) {
+		b.writeBits(uint64(v), 8)
+	}
+	return b.bytes()
+}
+
+// VORBIS_COMMENTメタデータブロックの内容
+// マーカーはプレイヤーが章として表示できるCHAPTERnnn / CHAPTERnnnNAMEで書き込む
+// 長さはVorbisの仕様に合わせてリトルエンディアンで書く
+func flacVorbisComment(metadata *WavMetadata, cues []CuePoint, sampleRate int) []byte {
+	var comments []string
+	if metadata != nil {
+		for _, f := range metadata.fields() {
+			comments = append(comments, strings.ToUpper(f[0])+"="+f[1])
+		}
+	}
+	for i, cue := range cues {
+		offset := time.Duration(cue.Frame * int64(time.Second) / int64(sampleRate))
+		comments = append(comments,
+			fmt.Sprintf("CHAPTER%03d=%02d:%02d:%02d.%03d", i+1,
+				int(offset.Hours()), int(offset.Minutes())%60, int(offset.Seconds())%60, offset.Milliseconds()%1000),
+			fmt.Sprintf("CHAPTER%03dNAME=%s", i+1, cue.Label))
+	}
+
+	body := binary.LittleEndian.AppendUint32(nil, uint32(len(metadataSoftware)))
+	body = append(body, metadataSoftware...)
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(comments)))
+	for _, comment := range comments {
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(comment)))
+		body = append(body, comment...)
+	}
+	return body
+}
+
+// 1ブロック分のフレームを書き込む
+func writeFlacFrame(b *bitWriter, block [][]int32, number uint64, bitsPerSample int) {
+	size := len(block[0])
+
+	// フレームヘッダー
+	b.writeBits(0x3FFE, 14) // 同期コード
+	b.writeBits(0, 1)       // 予約
+	b.writeBits(0, 1)       // 固定ブロックサイズ
+	b.writeBits(7, 4)       // ブロックサイズはヘッダー末尾に16bitで記録
+	b.writeBits(0, 4)       // サンプリングレートはSTREAMINFOを参照
+	b.writeBits(uint64(len(block)-1), 4)
+	b.writeBits(0, 3) // ビット深度はSTREAMINFOを参照
+	b.writeBits(0, 1) // 予約
+	b.writeUTF8(number)
+	b.writeBits(uint64(size-1), 16)
+	b.writeBits(uint64(crc8(b.bytes())), 8)
+
+	for _, channel := range block {
+		writeFlacSubframe(b, channel, bitsPerSample)
+	}
+
+	b.alignByte()
+	b.writeBits(uint64(crc16(b.bytes())), 16)
+}
+
+// 1チャンネル分のサブフレームを書き込む
+// 全て同じ値ならCONSTANT、圧縮できればFIXED、できなければVERBATIMで書き込む
+func writeFlacSubframe(b *bitWriter, samples []int32, bitsPerSample int) {
+	constant := true
+	for _, s := range samples[1:] {
+		if s != samples[0] {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		b.writeBits(0, 8) // パディング + CONSTANT + 無駄ビットなし
+		b.writeSigned(int64(samples[0]), uint(bitsPerSample))
+		return
+	}
+
+	// 残差の絶対値の合計が最小になる予測次数を選ぶ
+	order, residual := bestFixedOrder(samples)
+	params, partitionOrder, residualBits := bestRicePartition(residual, len(samples), order)
+
+	verbatimBits := len(samples) * bitsPerSample
+	if order*bitsPerSample+residualBits >= verbatimBits {
+		b.writeBits(1<<1, 8) // VERBATIM
+		for _, s := range samples {
+			b.writeSigned(int64(s), uint(bitsPerSample))
+		}
+		return
+	}
+
+	b.writeBits(uint64(8+order)<<1, 8) // FIXED
+	for _, s := range samples[:order] {
+		b.writeSigned(int64(s), uint(bitsPerSample))
+	}
+
+	// 残差（4bitのRiceパラメータ）
+	b.writeBits(0, 2)
+	b.writeBits(uint64(partitionOrder), 4)
+	pos := 0
+	for p, k := range params {
+		n := len(samples) >> partitionOrder
+		if p == 0 {
+			n -= order
+		}
+		b.writeBits(uint64(k), 4)
+		for _, r := range residual[pos : pos+n] {
+			b.writeRice(zigzag(r), k)
+		}
+		pos += n
+	}
+}
+
+// 固定予測の次数ごとに残差を求め、最も小さくなる次数とその残差を返す
+func bestFixedOrder(samples []int32) (int, []int64) {
+	maxOrder := flacMaxFixedOrder
+	if maxOrder > len(samples)-1 {
+		maxOrder = len(samples) - 1
+	}
+
+	bestOrder := 0
+	var best []int64
+	var bestSum uint64
+	for order := 0; order <= maxOrder; order++ {
+		residual := make([]int64, len(samples)-order)
+		var sum uint64
+		for i := order; i < len(samples); i++ {
+			r := fixedResidual(samples, i, order)
+			residual[i-order] = r
+			if r < 0 {
+				sum += uint64(-r)
+			} else {
+				sum += uint64(r)
+			}
+		}
+		if best == nil || sum < bestSum {
+			bestOrder, best, bestSum = order, residual, sum
+		}
+	}
+	return bestOrder, best
+}
+
+// 固定予測の残差
+func fixedResidual(s []int32, i, order int) int64 {
+	switch order {
+	case 0:
+		return int64(s[i])
+	case 1:
+		return int64(s[i]) - int64(s[i-1])
+	case 2:
+		return int64(s[i]) - 2*int64(s[i-1]) + int64(s[i-2])
+	case 3:
+		return int64(s[i]) - 3*int64(s[i-1]) + 3*int64(s[i-2]) - int64(s[i-3])
+	default:
+		return int64(s[i]) - 4*int64(s[i-1]) + 6*int64(s[i-2]) - 4*int64(s[i-3]) + int64(s[i-4])
+	}
+}
+
+// 残差のビット数が最小になるパーティション分割とRiceパラメータを選ぶ
+func bestRicePartition(residual []int64, blockSize, order int) ([]int, int, int) {
+	var bestParams []int
+	bestOrder, bestBits := 0, -1
+
+	for partitionOrder := 0; partitionOrder <= flacMaxPartitionOrder; partitionOrder++ {
+		partitions := 1 << partitionOrder
+		if blockSize%partitions != 0 || blockSize>>partitionOrder <= order {
+			break
+		}
+
+		params := make([]int, partitions)
+		total, pos := 0, 0
+		for p := range params {
+			n := blockSize >> partitionOrder
+			if p == 0 {
+				n -= order
+			}
+			k, size := bestRiceParam(residual[pos : pos+n])
+			params[p] = k
+			total += 4 + size
+			pos += n
+		}
+
+		if bestBits < 0 || total < bestBits {
+			bestParams, bestOrder, bestBits = params, partitionOrder, total
+		}
+	}
+	return bestParams, bestOrder, bestBits + 6
+}
+
+// パーティションのRiceパラメータとビット数（平均値から候補を絞って比較する）
+// 4bitのRiceパラメータは14までしか使えないため、大きな残差でもk=14として実際のビット数を返す
+func bestRiceParam(residual []int64) (int, int) {
+	if len(residual) == 0 {
+		return 0, 0
+	}
+	var sum uint64
+	for _, r := range residual {
+		sum += zigzag(r)
+	}
+	mean := sum / uint64(len(residual))
+	guess := 0
+	if mean > 0 {
+		guess = min(bits.Len64(mean)-1, flacMaxRiceParam)
+	}
+
+	bestK, bestSize := 0, -1
+	for k := max(guess-1, 0); k <= min(guess+1, flacMaxRiceParam); k++ {
+		size := len(residual) * (k + 1)
+		for _, r := range residual {
+			size += int(zigzag(r) >> uint(k))
+		}
+		if bestSize < 0 || size < bestSize {
+			bestK, bestSize = k, size
+		}
+	}
+	return bestK, bestSize
+}
+
+// 符号付きの値を符号なしに変換（0, -1, 1, -2, ... → 0, 1, 2, 3, ...）
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+// bitWriterはMSBから順にビットを書き込む
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (b *bitWriter) reset() {
+	b.buf = b.buf[:0]
+	b.acc = 0
+	b.nbits = 0
+}
+
+// 下位nビットを書き込む（nは32以下）
+func (b *bitWriter) writeBits(v uint64, n uint) {
+	for n > 32 {
+		b.writeBits(v>>32, n-32)
+		v &= 1<<32 - 1
+		n = 32
+	}
+	b.acc = b.acc<<n | v&(1<<n-1)
+	b.nbits += n
+	for b.nbits >= 8 {
+		b.nbits -= 8
+		b.buf = append(b.buf, byte(b.acc>>b.nbits))
+	}
+}
+
+// 2の補数でnビット書き込む
+func (b *bitWriter) writeSigned(v int64, n uint) {
+	b.writeBits(uint64(v), n)
+}
+
+// Rice符号（上位をunary、下位kビットをそのまま）
+func (b *bitWriter) writeRice(u uint64, k int) {
+	q := u >> uint(k)
+	for ; q >= 32; q -= 32 {
+		b.writeBits(0, 32)
+	}
+	b.writeBits(1, uint(q)+1)
+	if k > 0 {
+		b.writeBits(u, uint(k))
+	}
+}
+
+// フレーム番号をUTF-8形式で書き込む
+func (b *bitWriter) writeUTF8(v uint64) {
+	if v < 0x80 {
+		b.writeBits(v, 8)
+		return
+	}
+	n := 2
+	for v >= 1<<(5*n+1) {
+		n++
+	}
+	b.writeBits(uint64(0xFF00>>n)&0xFF|v>>(6*(n-1)), 8)
+	for i := n - 2; i >= 0; i-- {
+		b.writeBits(0x80|(v>>(6*i))&0x3F, 8)
+	}
+}
+
+// バイト境界までゼロで埋める
+func (b *bitWriter) alignByte() {
+	if b.nbits > 0 {
+		b.writeBits(0, 8-b.nbits)
+	}
+}
+
+// 書き込んだバイト列（端数のビットは含まない）
+func (b *bitWriter) bytes() []byte {
+	return b.buf
+}
+
+// CRC-8（多項式 x^8 + x^2 + x + 1）
+func crc8(data []byte) uint8 {
+	var crc uint8
+	for _, v := range data {
+		crc ^= v
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// CRC-16（多項式 x^16 + x^15 + x^2 + 1）
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, v := range data {
+		crc ^= uint16(v) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
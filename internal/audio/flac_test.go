@@ -0,0 +1,140 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// 全振幅のホワイトノイズ（残差が大きく、Riceパラメータが上限に張り付く）
+func fullScaleNoise(frames, channels, bitsPerSample int, seed int64) []int32 {
+	rng := rand.New(rand.NewSource(seed))
+	maxValue := int64(1)<<(bitsPerSample-1) - 1
+	samples := make([]int32, frames*channels)
+	for i := range samples {
+		samples[i] = int32(rng.Int63n(2*maxValue+2) - maxValue - 1)
+	}
+	return samples
+}
+
+func TestFlacRoundTrip(t *testing.T) {
+	sine := func(frames, channels, bitsPerSample int) []int32 {
+		amplitude := float64(int32(1)<<(bitsPerSample-1) - 1)
+		samples := make([]int32, frames*channels)
+		for i := range samples {
+			samples[i] = int32(amplitude * 0.5 * math.Sin(float64(i/channels)*0.01*float64(1+i%channels)))
+		}
+		return samples
+	}
+
+	tests := []struct {
+		name          string
+		channels      int
+		bitsPerSample int
+		samples       []int32
+	}{
+		{"noise16_mono", 1, 16, fullScaleNoise(10000, 1, 16, 1)},
+		{"noise16_stereo", 2, 16, fullScaleNoise(10000, 2, 16, 2)},
+		{"noise24_mono", 1, 24, fullScaleNoise(10000, 1, 24, 3)},
+		{"noise24_stereo", 2, 24, fullScaleNoise(10000, 2, 24, 4)},
+		{"sine16", 2, 16, sine(9000, 2, 16)},
+		{"sine24", 1, 24, sine(9000, 1, 24)},
+		{"silence", 1, 16, make([]int32, 5000)},
+		{"short", 1, 16, []int32{1, -1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeFlac(&buf, tt.samples, 48000, tt.channels, tt.bitsPerSample, FlacOptions{}); err != nil {
+				t.Fatalf("EncodeFlac: %v", err)
+			}
+
+			// 圧縮できない信号でもVERBATIMより大きくならない（ヘッダーとフレームの余白を除く）
+			frames := len(tt.samples) / tt.channels
+			blocks := (frames + flacBlockSize - 1) / flacBlockSize
+			limit := len(tt.samples)*tt.bitsPerSample/8 + blocks*(16+tt.channels) + 64
+			if buf.Len() > limit {
+				t.Errorf("FLACが大きすぎます: %d バイト（上限 %d）", buf.Len(), limit)
+			}
+
+			decoded, format, err := DecodeFlac(buf.Bytes())
+			if err != nil {
+				t.Fatalf("DecodeFlac: %v", err)
+			}
+			if format.Channels != tt.channels || format.BitsPerSample != tt.bitsPerSample || format.SampleRate != 48000 {
+				t.Errorf("形式が違います: %+v", format)
+			}
+			if len(decoded) != len(tt.samples) {
+				t.Fatalf("サンプル数が違います: %d（期待値 %d）", len(decoded), len(tt.samples))
+			}
+			for i := range decoded {
+				if decoded[i] != tt.samples[i] {
+					t.Fatalf("サンプル %d が違います: %d（期待値 %d）", i, decoded[i], tt.samples[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBestRiceParamLargeResidual(t *testing.T) {
+	// 24bitのノイズの残差はk=14でも収まらないが、実際のビット数を返す
+	residual := []int64{1 << 26, -(1 << 26), 1<<27 - 1}
+	k, size := bestRiceParam(residual)
+	if k != flacMaxRiceParam {
+		t.Errorf("k = %d（期待値 %d）", k, flacMaxRiceParam)
+	}
+	want := 0
+	for _, r := range residual {
+		want += int(zigzag(r)>>flacMaxRiceParam) + 1 + flacMaxRiceParam
+	}
+	if size != want {
+		t.Errorf("ビット数 = %d（期待値 %d）", size, want)
+	}
+}
+
+func TestConvertToFlac(t *testing.T) {
+	dir := t.TempDir()
+	buffer := [][]float32{make([]float32, 4800)}
+	rng := rand.New(rand.NewSource(5))
+	for i := range buffer[0] {
+		buffer[0][i] = rng.Float32()*2 - 1
+	}
+
+	// 24bitのWAVはそのままの値で変換される
+	wavPath := filepath.Join(dir, "pcm24.wav")
+	if _, err := WriteWavFile(wavPath, buffer, 48000, 2, WavOptions{Format: PCM24}); err != nil {
+		t.Fatal(err)
+	}
+	flacPath := filepath.Join(dir, "pcm24.flac")
+	if err := ConvertToFlac(wavPath, flacPath); err != nil {
+		t.Fatalf("ConvertToFlac: %v", err)
+	}
+	want, _, err := ReadWav(wavPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, format, err := ReadFlac(flacPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format.BitsPerSample != 24 || format.Channels != 2 || len(got) != len(want) {
+		t.Fatalf("形式が違います: %+v（%d サンプル）", format, len(got))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("サンプル %d が違います: %v（期待値 %v）", i, got[i], want[i])
+		}
+	}
+
+	// 浮動小数点のWAVは変換しない
+	floatPath := filepath.Join(dir, "float.wav")
+	if _, err := WriteWavFile(floatPath, buffer, 48000, 1, WavOptions{Format: Float32}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ConvertToFlac(floatPath, filepath.Join(dir, "float.flac")); !errors.Is(err, ErrFlacLossy) {
+		t.Errorf("浮動小数点のWAVでErrFlacLossyが返りません: %v", err)
+	}
+}
@@ -0,0 +1,610 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsFlacは拡張子がFLACのファイルかどうかを返す
+func IsFlac(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".flac")
+}
+
+// ReadAudioはWAVまたはFLACファイルを読み込み、インターリーブされたサンプルと形式を返す
+func ReadAudio(path string) ([]float32, WavFormat, error) {
+	if IsFlac(path) {
+		return ReadFlac(path)
+	}
+	return ReadWav(path)
+}
+
+// ReadFlacはFLACファイルを読み込み、インターリーブされたサンプル（-1.0〜1.0）と形式を返す
+func ReadFlac(path string) ([]float32, WavFormat, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, WavFormat{}, fmt.Errorf("FLACファイルを読み込めませんでした: %v", err)
+	}
+
+	pcm, format, err := DecodeFlac(data)
+	if err != nil {
+		return nil, format, fmt.Errorf("%s: %v", path, err)
+	}
+
+	scale := 1 / float32(int64(1)<<(format.BitsPerSample-1))
+	samples := make([]float32, len(pcm))
+	for i, v := range pcm {
+		samples[i] = float32(v) * scale
+	}
+	return samples, format, nil
+}
+
+// DecodeFlacはFLACのデータを整数のインターリーブされたサンプルに復号する
+// 固定予測・線形予測・ステレオの相関除去・可変ブロックサイズに対応する
+func DecodeFlac(data []byte) ([]int32, WavFormat, error) {
+	var format WavFormat
+
+	// 先頭のID3タグは読み飛ばす
+	if len(data) >= 10 && string(data[0:3]) == "ID3" {
+		size := int(data[6]&0x7F)<<21 | int(data[7]&0x7F)<<14 | int(data[8]&0x7F)<<7 | int(data[9]&0x7F)
+		if 10+size > len(data) {
+			return nil, format, fmt.Errorf("FLACファイルではありません")
+		}
+		data = data[10+size:]
+	}
+	if len(data) < 4 || string(data[0:4]) != "fLaC" {
+		return nil, format, fmt.Errorf("FLACファイルではありません")
+	}
+
+	// メタデータブロック（STREAMINFOとVORBIS_COMMENT以外は読み飛ばす）
+	reader := bytes.NewReader(data[4:])
+	format, totalFrames, err := readFlacMetadata(reader)
+	if err != nil {
+		return nil, format, err
+	}
+	pos := len(data) - reader.Len()
+
+	// 総フレーム数は領域確保の目安にのみ使う（不明・不正な値に備えて上限を設ける）
+	capacity := totalFrames * uint64(format.Channels)
+	if limit := uint64(len(data)) * 8; capacity > limit {
+		capacity = limit
+	}
+	samples := make([]int32, 0, capacity)
+	r := &bitReader{data: data, pos: pos}
+	for r.remaining() >= 2 {
+		var err error
+		samples, err = decodeFlacFrame(r, samples, format)
+		if err != nil {
+			// 途中で壊れている場合はそこまでを返す
+			if len(samples) > 0 {
+				break
+			}
+			return nil, format, err
+		}
+	}
+
+	format.Frames = len(samples) / format.Channels
+	return samples, format, nil
+}
+
+// FLACのメタデータを読み込み、形式と総フレーム数を返す（rは"fLaC"の直後）
+func readFlacMetadata(r io.Reader) (WavFormat, uint64, error) {
+	var format WavFormat
+	var totalFrames uint64
+	haveInfo := false
+	cues := make(map[uint32]*CuePoint)
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return format, 0, fmt.Errorf("メタデータが途中で終わっています")
+		}
+		last := header[0]&0x80 != 0
+		blockType := header[0] & 0x7F
+		length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+
+		switch blockType {
+		case 0, 2, 4:
+			block := make([]byte, length)
+			if _, err := io.ReadFull(r, block); err != nil {
+				return format, 0, fmt.Errorf("メタデータが途中で終わっています")
+			}
+			if blockType == 4 {
+				format.Metadata = parseVorbisComment(block)
+				break
+			}
+			if blockType == 2 {
+				// APPLICATIONブロックに入れたRIFFのcue/lablチャンク
+				if len(block) >= 4 && string(block[0:4]) == "riff" {
+					parseRiffChunks(block[4:], &format, cues)
+				}
+				break
+			}
+			if length < 34 {
+				return format, 0, fmt.Errorf("STREAMINFOが不正です")
+			}
+			packed := binary.BigEndian.Uint64(block[10:18])
+			format.SampleRate = int(packed >> 44)
+			format.Channels = int(packed>>41&0x7) + 1
+			format.BitsPerSample = int(packed>>36&0x1F) + 1
+			totalFrames = packed & (1<<36 - 1)
+			haveInfo = true
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(length)); err != nil {
+				return format, 0, fmt.Errorf("メタデータが途中で終わっています")
+			}
+		}
+
+		if last {
+			break
+		}
+	}
+	if !haveInfo {
+		return format, 0, fmt.Errorf("STREAMINFOが見つかりません")
+	}
+	format.Cues = sortedCues(cues)
+	return format, totalFrames, nil
+}
+
+// 並んだRIFFのチャンクからメタデータとマーカーを読み取る
+func parseRiffChunks(data []byte, format *WavFormat, cues map[uint32]*CuePoint) {
+	for pos := 0; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		pos += 8
+		if size > len(data)-pos {
+			return
+		}
+		parseInfoChunks(id, data[pos:pos+size], format, cues)
+		pos += size + size%2
+	}
+}
+
+// VORBIS_COMMENTからセッションのメタデータを読み取る（ない場合はnil）
+func parseVorbisComment(block []byte) *WavMetadata {
+	next := func() (string, bool) {
+		if len(block) < 4 {
+			return "", false
+		}
+		n := int(binary.LittleEndian.Uint32(block))
+		if n > len(block)-4 {
+			return "", false
+		}
+		s := string(block[4 : 4+n])
+		block = block[4+n:]
+		return s, true
+	}
+
+	if _, ok := next(); !ok { // ベンダー文字列
+		return nil
+	}
+	if len(block) < 4 {
+		return nil
+	}
+	count := int(binary.LittleEndian.Uint32(block))
+	block = block[4:]
+
+	metadata := &WavMetadata{}
+	for i := 0; i < count; i++ {
+		comment, ok := next()
+		if !ok {
+			break
+		}
+		if key, value, ok := strings.Cut(comment, "="); ok {
+			metadata.set(key, value)
+		}
+	}
+	if metadata.SessionID == "" {
+		return nil
+	}
+	return metadata
+}
+
+// サンプルを復号せずにFLACの形式とメタデータを読み込む
+func readFlacInfo(r io.Reader) (WavFormat, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil || string(magic[:]) != "fLaC" {
+		return WavFormat{}, fmt.Errorf("FLACファイルではありません")
+	}
+	format, totalFrames, err := readFlacMetadata(r)
+	format.Frames = int(totalFrames)
+	return format, err
+}
+
+// 1フレームを復号してsamplesに追加する
+func decodeFlacFrame(r *bitReader, samples []int32, stream WavFormat) ([]int32, error) {
+	start := r.bytePos()
+	if r.readBits(14) != 0x3FFE {
+		return samples, fmt.Errorf("フレームの同期コードが見つかりません")
+	}
+	r.readBits(1) // 予約
+	r.readBits(1) // ブロックサイズの方式（固定/可変）
+	blockSizeCode := r.readBits(4)
+	sampleRateCode := r.readBits(4)
+	assignment := int(r.readBits(4))
+	bitsCode := r.readBits(3)
+	r.readBits(1) // 予約
+	if _, ok := r.readUTF8(); !ok {
+		return samples, fmt.Errorf("フレーム番号が不正です")
+	}
+
+	var blockSize int
+	switch {
+	case blockSizeCode == 1:
+		blockSize = 192
+	case blockSizeCode >= 2 && blockSizeCode <= 5:
+		blockSize = 576 << (blockSizeCode - 2)
+	case blockSizeCode == 6:
+		blockSize = int(r.readBits(8)) + 1
+	case blockSizeCode == 7:
+		blockSize = int(r.readBits(16)) + 1
+	case blockSizeCode >= 8:
+		blockSize = 256 << (blockSizeCode - 8)
+	default:
+		return samples, fmt.Errorf("ブロックサイズが不正です")
+	}
+
+	switch sampleRateCode {
+	case 12:
+		r.readBits(8)
+	case 13, 14:
+		r.readBits(16)
+	case 15:
+		return samples, fmt.Errorf("サンプリングレートが不正です")
+	}
+
+	bitsPerSample := stream.BitsPerSample
+	switch bitsCode {
+	case 1:
+		bitsPerSample = 8
+	case 2:
+		bitsPerSample = 12
+	case 4:
+		bitsPerSample = 16
+	case 5:
+		bitsPerSample = 20
+	case 6:
+		bitsPerSample = 24
+	case 7:
+		bitsPerSample = 32
+	case 3:
+		return samples, fmt.Errorf("ビット深度が不正です")
+	}
+
+	channels := assignment + 1
+	if assignment >= 8 {
+		if assignment > 10 {
+			return samples, fmt.Errorf("チャンネル割り当てが不正です")
+		}
+		channels = 2
+	}
+	if channels != stream.Channels {
+		return samples, fmt.Errorf("チャンネル数がSTREAMINFOと一致しません")
+	}
+
+	// ヘッダーのCRC-8
+	if crc := crc8(r.data[start:r.bytePos()]); uint8(r.readBits(8)) != crc {
+		return samples, fmt.Errorf("フレームヘッダーのCRCが一致しません")
+	}
+
+	// サブフレーム（相関除去のside チャンネルは1ビット多い）
+	block := make([][]int64, channels)
+	for c := range block {
+		sampleBits := bitsPerSample
+		if (assignment == 8 && c == 1) || (assignment == 9 && c == 0) || (assignment == 10 && c == 1) {
+			sampleBits++
+		}
+		var err error
+		block[c], err = decodeFlacSubframe(r, blockSize, sampleBits)
+		if err != nil {
+			return samples, err
+		}
+	}
+
+	// フッターのCRC-16
+	r.alignByte()
+	end := r.bytePos()
+	if r.remaining() < 2 {
+		return samples, fmt.Errorf("フレームが途中で終わっています")
+	}
+	if crc := crc16(r.data[start:end]); uint16(r.readBits(16)) != crc {
+		return samples, fmt.Errorf("フレームのCRCが一致しません")
+	}
+
+	// ステレオの相関除去を元に戻す
+	switch assignment {
+	case 8: // left/side
+		for i := range block[1] {
+			block[1][i] = block[0][i] - block[1][i]
+		}
+	case 9: // side/right
+		for i := range block[0] {
+			block[0][i] += block[1][i]
+		}
+	case 10: // mid/side
+		for i := range block[0] {
+			side := block[1][i]
+			mid := block[0][i]<<1 | side&1
+			block[0][i] = (mid + side) >> 1
+			block[1][i] = (mid - side) >> 1
+		}
+	}
+
+	// ストリームと異なるビット深度のフレームはストリームの深度に合わせる
+	shift := stream.BitsPerSample - bitsPerSample
+	for i := 0; i < blockSize; i++ {
+		for c := range block {
+			v := block[c][i]
+			if shift > 0 {
+				v <<= uint(shift)
+			} else if shift < 0 {
+				v >>= uint(-shift)
+			}
+			samples = append(samples, int32(v))
+		}
+	}
+	return samples, nil
+}
+
+// 1チャンネル分のサブフレームを復号する
+func decodeFlacSubframe(r *bitReader, blockSize, sampleBits int) ([]int64, error) {
+	if r.readBits(1) != 0 {
+		return nil, fmt.Errorf("サブフレームのパディングが不正です")
+	}
+	subframeType := int(r.readBits(6))
+
+	// 無駄ビット（全サンプルの下位に共通する0のビット）
+	wasted := 0
+	if r.readBits(1) == 1 {
+		wasted = int(r.readUnary()) + 1
+		sampleBits -= wasted
+	}
+
+	out := make([]int64, blockSize)
+	switch {
+	case subframeType == 0: // CONSTANT
+		v := r.readSigned(sampleBits)
+		for i := range out {
+			out[i] = v
+		}
+	case subframeType == 1: // VERBATIM
+		for i := range out {
+			out[i] = r.readSigned(sampleBits)
+		}
+	case subframeType >= 8 && subframeType <= 12: // FIXED
+		order := subframeType - 8
+		if order > blockSize {
+			return nil, fmt.Errorf("予測次数がブロックサイズを超えています")
+		}
+		for i := 0; i < order; i++ {
+			out[i] = r.readSigned(sampleBits)
+		}
+		if err := decodeFlacResidual(r, out, order); err != nil {
+			return nil, err
+		}
+		for i := order; i < blockSize; i++ {
+			switch order {
+			case 1:
+				out[i] += out[i-1]
+			case 2:
+				out[i] += 2*out[i-1] - out[i-2]
+			case 3:
+				out[i] += 3*out[i-1] - 3*out[i-2] + out[i-3]
+			case 4:
+				out[i] += 4*out[i-1] - 6*out[i-2] + 4*out[i-3] - out[i-4]
+			}
+		}
+	case subframeType >= 32: // LPC
+		order := subframeType - 31
+		if order > blockSize {
+			return nil, fmt.Errorf("予測次数がブロックサイズを超えています")
+		}
+		for i := 0; i < order; i++ {
+			out[i] = r.readSigned(sampleBits)
+		}
+		precision := int(r.readBits(4)) + 1
+		if precision == 16 {
+			return nil, fmt.Errorf("線形予測係数の精度が不正です")
+		}
+		shift := r.readSigned(5)
+		if shift < 0 {
+			return nil, fmt.Errorf("線形予測のシフト量が不正です")
+		}
+		coefs := make([]int64, order)
+		for i := range coefs {
+			coefs[i] = r.readSigned(precision)
+		}
+		if err := decodeFlacResidual(r, out, order); err != nil {
+			return nil, err
+		}
+		for i := order; i < blockSize; i++ {
+			var sum int64
+			for j, c := range coefs {
+				sum += c * out[i-j-1]
+			}
+			out[i] += sum >> uint(shift)
+		}
+	default:
+		return nil, fmt.Errorf("未対応のサブフレームです: %d", subframeType)
+	}
+
+	if wasted > 0 {
+		for i := range out {
+			out[i] <<= uint(wasted)
+		}
+	}
+	if r.overrun() {
+		return nil, fmt.Errorf("フレームが途中で終わっています")
+	}
+	return out, nil
+}
+
+// Rice符号の残差を復号してout[order:]に格納する
+func decodeFlacResidual(r *bitReader, out []int64, order int) error {
+	method := r.readBits(2)
+	if method > 1 {
+		return fmt.Errorf("未対応の残差符号化方式です: %d", method)
+	}
+	paramBits, escape := 4, uint64(15)
+	if method == 1 {
+		paramBits, escape = 5, 31
+	}
+
+	partitionOrder := int(r.readBits(4))
+	partitions := 1 << partitionOrder
+	if len(out)%partitions != 0 || len(out)>>partitionOrder < order {
+		return fmt.Errorf("残差のパーティションが不正です")
+	}
+
+	i := order
+	for p := 0; p < partitions; p++ {
+		n := len(out) >> partitionOrder
+		if p == 0 {
+			n -= order
+		}
+		k := r.readBits(paramBits)
+		if k == escape {
+			// エスケープ（固定ビット数の符号付き整数）
+			rawBits := int(r.readBits(5))
+			for j := 0; j < n; j++ {
+				if rawBits == 0 {
+					out[i] = 0
+				} else {
+					out[i] = r.readSigned(rawBits)
+				}
+				i++
+			}
+			continue
+		}
+		for j := 0; j < n; j++ {
+			u := r.readUnary()<<k | r.readBits(int(k))
+			out[i] = int64(u>>1) ^ -int64(u&1)
+			i++
+		}
+		if r.overrun() {
+			return fmt.Errorf("フレームが途中で終わっています")
+		}
+	}
+	return nil
+}
+
+// bitReaderはMSBから順にビットを読み出す
+// データの終端を超えて読んだ場合は0を返し、overrunで検出する
+type bitReader struct {
+	data  []byte
+	pos   int // 次に読むバイトの位置
+	acc   uint64
+	nbits int
+	over  bool
+}
+
+// 残りのバイト数（バイト境界にいる場合）
+func (r *bitReader) remaining() int {
+	return len(r.data) - r.pos + r.nbits/8
+}
+
+// 現在のバイト位置（バイト境界にいる場合）
+func (r *bitReader) bytePos() int {
+	return r.pos - r.nbits/8
+}
+
+func (r *bitReader) overrun() bool {
+	return r.over
+}
+
+// 蓄積しているビットがn以上になるまで読み込む
+func (r *bitReader) fill(n int) {
+	for r.nbits < n {
+		var b byte
+		if r.pos < len(r.data) {
+			b = r.data[r.pos]
+		} else {
+			r.over = true
+		}
+		r.pos++
+		r.acc = r.acc<<8 | uint64(b)
+		r.nbits += 8
+	}
+}
+
+// nビット（32以下）を読み出す
+func (r *bitReader) readBits(n int) uint64 {
+	if n == 0 {
+		return 0
+	}
+	r.fill(n)
+	r.nbits -= n
+	return r.acc >> uint(r.nbits) & (1<<uint(n) - 1)
+}
+
+// nビットの2の補数を読み出す
+func (r *bitReader) readSigned(n int) int64 {
+	if n == 0 {
+		return 0
+	}
+	v := r.readBits(n)
+	return int64(v<<(64-uint(n))) >> (64 - uint(n))
+}
+
+// 1が現れるまでの0の数を読み出す
+func (r *bitReader) readUnary() uint64 {
+	var count uint64
+	for {
+		if r.nbits == 0 {
+			if r.over {
+				return count
+			}
+			r.fill(8)
+		}
+		window := r.acc & (1<<uint(r.nbits) - 1)
+		if window == 0 {
+			count += uint64(r.nbits)
+			r.nbits = 0
+			continue
+		}
+		zeros := r.nbits - bits.Len64(window)
+		count += uint64(zeros)
+		r.nbits -= zeros + 1
+		return count
+	}
+}
+
+// UTF-8形式の数値を読み出す
+func (r *bitReader) readUTF8() (uint64, bool) {
+	first := r.readBits(8)
+	if first&0x80 == 0 {
+		return first, true
+	}
+	n := bits.LeadingZeros8(^uint8(first))
+	if n < 2 || n > 7 {
+		return 0, false
+	}
+	v := first & (0xFF >> uint(n+1))
+	for i := 1; i < n; i++ {
+		b := r.readBits(8)
+		if b&0xC0 != 0x80 {
+			return 0, false
+		}
+		v = v<<6 | b&0x3F
+	}
+	return v, true
+}
+
+// 次のバイト境界まで読み飛ばす
+func (r *bitReader) alignByte() {
+	r.nbits -= r.nbits % 8
+}
+
+// ConvertToWavはWAVまたはFLACファイルを16bitのWAVファイルに変換する（メタデータは引き継ぐ）
+func ConvertToWav(srcPath, wavPath string) error {
+	samples, format, err := ReadAudio(srcPath)
+	if err != nil {
+		return err
+	}
+	_, err = WriteWavFile(wavPath, [][]float32{samples}, format.SampleRate, format.Channels, WavOptions{Metadata: format.Metadata})
+	return err
+}
@@ -0,0 +1,323 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// メタデータを書き込んだソフトウェア名
+const metadataSoftware = "whisper_recorder"
+
+// bextチャンクの固定部分の長さ（Description〜Reserved）
+const bextSize = 602
+
+// WavMetadataはセグメントと録音セッションの対応を示すメタデータ
+// WAVではLIST/INFOとbext、FLACではVORBIS_COMMENTに保存する
+type WavMetadata struct {
+	SessionID   string        // 録音セッションのID
+	Sequence    int           // セッション内の通し番号
+	Offset      time.Duration // セッション開始からの開始位置
+	Device      string        // 録音デバイス名
+	CaptureTime time.Time     // 録音を開始した時刻
+	Bookmarks   []Marker      // セグメント内のブックマーク
+}
+
+// Markerはセッション内の位置に付けたラベル
+type Marker struct {
+	Offset time.Duration // セッション開始からの位置
+	Label  string        // ラベル
+}
+
+// メタデータのキーと値（WAVのコメントとFLACのVORBIS_COMMENTで共通）
+func (m *WavMetadata) fields() [][2]string {
+	fields := [][2]string{
+		{"session", m.SessionID},
+		{"sequence", strconv.Itoa(m.Sequence)},
+		{"offset", strconv.FormatInt(m.Offset.Milliseconds(), 10) + "ms"},
+	}
+	if !m.CaptureTime.IsZero() {
+		fields = append(fields, [2]string{"captured", m.CaptureTime.Format(time.RFC3339)})
+	}
+	if m.Device != "" {
+		fields = append(fields, [2]string{"device", m.Device})
+	}
+	for _, b := range m.Bookmarks {
+		fields = append(fields, [2]string{"bookmark", strconv.FormatInt(b.Offset.Milliseconds(), 10) + "ms " + b.Label})
+	}
+	return fields
+}
+
+// key=value を1行ずつ並べたテキスト
+func (m *WavMetadata) text() string {
+	lines := make([]string, 0, 5+len(m.Bookmarks))
+	for _, f := range m.fields() {
+		lines = append(lines, f[0]+"="+f[1])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// key=value の1項目を解釈する（未知のキーは無視してfalseを返す）
+func (m *WavMetadata) set(key, value string) bool {
+	switch strings.ToLower(key) {
+	case "session":
+		m.SessionID = value
+	case "sequence":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return false
+		}
+		m.Sequence = n
+	case "offset":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return false
+		}
+		m.Offset = d
+	case "captured":
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return false
+		}
+		m.CaptureTime = t
+	case "device":
+		m.Device = value
+	case "bookmark":
+		offset, label, _ := strings.Cut(value, " ")
+		d, err := time.ParseDuration(offset)
+		if err != nil {
+			return false
+		}
+		m.Bookmarks = append(m.Bookmarks, Marker{Offset: d, Label: label})
+	default:
+		return false
+	}
+	return true
+}
+
+// key=value の行を解釈し、セッションIDが見つかった場合はtrueを返す
+func (m *WavMetadata) parseText(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		if key, value, ok := strings.Cut(strings.TrimRight(line, "\r\x00"), "="); ok {
+			m.set(key, value)
+		}
+	}
+	return m.SessionID != ""
+}
+
+// LIST/INFOとbextチャンクを作成（fmtとdataの間に書き込む）
+func (m *WavMetadata) wavChunks(sampleRate int) []byte {
+	var out []byte
+
+	// LIST/INFO: 一般的なプレイヤーでも表示される項目
+	info := []byte("INFO")
+	infoItems := [][2]string{
+		{"INAM", fmt.Sprintf("%s #%d", m.SessionID, m.Sequence)},
+		{"IPRD", m.SessionID},
+		{"ITRK", strconv.Itoa(m.Sequence)},
+		{"ISFT", metadataSoftware},
+		{"ICMT", m.text()},
+	}
+	if !m.CaptureTime.IsZero() {
+		infoItems = append(infoItems, [2]string{"ICRD", m.CaptureTime.Format(time.RFC3339)})
+	}
+	for _, item := range infoItems {
+		info = appendChunk(info, item[0], append([]byte(item[1]), 0))
+	}
+	out = appendChunk(out, "LIST", info)
+
+	// bext（Broadcast Wave Format）: TimeReferenceはセッション開始からのサンプル数
+	bext := make([]byte, bextSize)
+	putFixedString(bext[0:256], m.text())
+	putFixedString(bext[256:288], metadataSoftware)
+	putFixedString(bext[288:320], fmt.Sprintf("%s_%04d", m.SessionID, m.Sequence))
+	if !m.CaptureTime.IsZero() {
+		putFixedString(bext[320:330], m.CaptureTime.Format("2006-01-02"))
+		putFixedString(bext[330:338], m.CaptureTime.Format("15:04:05"))
+	}
+	timeReference := uint64(m.Offset) * uint64(sampleRate) / uint64(time.Second)
+	binary.LittleEndian.PutUint64(bext[338:346], timeReference)
+	binary.LittleEndian.PutUint16(bext[346:348], 1) // バージョン
+	out = appendChunk(out, "bext", bext)
+
+	return out
+}
+
+// チャンクを追加（奇数長の場合はパディングを付ける）
+func appendChunk(dst []byte, id string, body []byte) []byte {
+	dst = append(dst, id...)
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(body)))
+	dst = append(dst, body...)
+	if len(body)%2 == 1 {
+		dst = append(dst, 0)
+	}
+	return dst
+}
+
+// 固定長の領域に文字列を書き込む（UTF-8の文字の途中では切らない）
+func putFixedString(dst []byte, s string) {
+	for len(s) > len(dst) {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	copy(dst, s)
+}
+
+// LIST/INFOチャンクからメタデータを読み取る
+func parseInfoChunk(body []byte, m *WavMetadata) bool {
+	if len(body) < 4 || string(body[0:4]) != "INFO" {
+		return false
+	}
+	found := false
+	for pos := 4; pos+8 <= len(body); {
+		id := string(body[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(body[pos+4 : pos+8]))
+		pos += 8
+		if size > len(body)-pos {
+			break
+		}
+		value := strings.TrimRight(string(body[pos:pos+size]), "\x00")
+		switch id {
+		case "ICMT":
+			// bextのDescriptionは途中で切れている場合があるため、先に読んでいてもICMTで読み直す
+			m.Bookmarks = nil
+			found = m.parseText(value)
+		case "IPRD":
+			if m.SessionID == "" {
+				m.SessionID = value
+			}
+		case "ITRK":
+			if m.Sequence == 0 {
+				m.Sequence, _ = strconv.Atoi(value)
+			}
+		}
+		pos += size + size%2
+	}
+	return found || m.SessionID != ""
+}
+
+// bextチャンクからメタデータを読み取る
+func parseBextChunk(body []byte, m *WavMetadata) bool {
+	if len(body) < 256 {
+		return false
+	}
+	// LIST/INFOのICMTから読み取り済みの場合はそちらを優先する
+	if m.SessionID != "" {
+		return true
+	}
+	return m.parseText(strings.TrimRight(string(body[0:256]), "\x00"))
+}
+
+// ReadAudioInfoはサンプルを復号せずにWAV・FLACファイルの形式とメタデータを読み込む
+func ReadAudioInfo(path string) (WavFormat, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return WavFormat{}, fmt.Errorf("オーディオファイルを読み込めませんでした: %v", err)
+	}
+	defer file.Close()
+
+	var format WavFormat
+	if IsFlac(path) {
+		format, err = readFlacInfo(bufio.NewReader(file))
+	} else {
+		format, err = readWavInfo(file)
+	}
+	if err != nil {
+		return format, fmt.Errorf("%s: %v", path, err)
+	}
+	return format, nil
+}
+
+// WAVのチャンクを走査して形式とメタデータを読み取る（dataチャンクは読み飛ばす）
+func readWavInfo(file *os.File) (WavFormat, error) {
+	var format WavFormat
+
+	info, err := file.Stat()
+	if err != nil {
+		return format, err
+	}
+	var header [12]byte
+	if _, err := io.ReadFull(file, header[:]); err != nil ||
+		string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return format, fmt.Errorf("WAVファイルではありません")
+	}
+
+	haveFormat, haveData := false, false
+	cues := make(map[uint32]*CuePoint)
+	pos := int64(12)
+	for pos+8 <= info.Size() {
+		var chunk [8]byte
+		if _, err := file.ReadAt(chunk[:], pos); err != nil {
+			break
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		body := pos + 8
+		if size > info.Size()-body {
+			// dataより前のチャンクが途中で終わっている場合はそのチャンクをエラーに示す
+			if id != "data" && !haveData {
+				return format, truncatedChunkError(id, size, info.Size()-body)
+			}
+			size = info.Size() - body
+		}
+
+		switch id {
+		case "fmt ", "LIST", "bext", "cue ":
+			data := make([]byte, size)
+			if _, err := file.ReadAt(data, body); err != nil {
+				return format, fmt.Errorf("%sチャンクが不正です", strings.TrimSpace(id))
+			}
+			if id == "fmt " {
+				if err := parseWavFormat(data, &format); err != nil {
+					return format, err
+				}
+				haveFormat = true
+			} else {
+				parseInfoChunks(id, data, &format, cues)
+			}
+		case "data":
+			if !haveFormat {
+				return format, fmt.Errorf("dataチャンクの前にfmtチャンクがありません")
+			}
+			format.Frames = int(size) / (format.BitsPerSample / 8 * format.Channels)
+			haveData = true
+		}
+		pos = body + size + size%2
+	}
+
+	if !haveFormat {
+		return format, fmt.Errorf("fmtチャンクが見つかりません")
+	}
+	if !haveData {
+		return format, fmt.Errorf("dataチャンクが見つかりません")
+	}
+	format.Cues = sortedCues(cues)
+	return format, nil
+}
+
+// 音声以外の情報のチャンク（LIST・bext・cue）を読み取る
+func parseInfoChunks(id string, body []byte, format *WavFormat, cues map[uint32]*CuePoint) {
+	metadata := format.Metadata
+	if metadata == nil {
+		metadata = &WavMetadata{}
+	}
+	switch id {
+	case "LIST":
+		if parseInfoChunk(body, metadata) {
+			format.Metadata = metadata
+		}
+		parseAdtlChunk(body, cues)
+	case "bext":
+		if parseBextChunk(body, metadata) {
+			format.Metadata = metadata
+		}
+	case "cue ":
+		parseCueChunk(body, cues)
+	}
+}
@@ -0,0 +1,128 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testMetadata() *WavMetadata {
+	return &WavMetadata{
+		SessionID:   "20250101_120000",
+		Sequence:    12,
+		Offset:      5*time.Minute + 30*time.Second + 250*time.Millisecond,
+		Device:      "MacBook Proのマイク",
+		CaptureTime: time.Date(2025, 1, 1, 12, 0, 0, 0, time.FixedZone("JST", 9*60*60)),
+		Bookmarks: []Marker{
+			{Offset: 5*time.Minute + 40*time.Second, Label: "予算の結論"},
+			{Offset: 5*time.Minute + 50*time.Second, Label: ""},
+		},
+	}
+}
+
+func checkMetadata(t *testing.T, got *WavMetadata, want *WavMetadata) {
+	t.Helper()
+	if got == nil {
+		t.Fatal("メタデータが読み込まれません")
+	}
+	if got.SessionID != want.SessionID || got.Sequence != want.Sequence || got.Offset != want.Offset || got.Device != want.Device {
+		t.Errorf("メタデータ = %+v（期待値 %+v）", got, want)
+	}
+	if !got.CaptureTime.Equal(want.CaptureTime) {
+		t.Errorf("録音開始時刻 = %v（期待値 %v）", got.CaptureTime, want.CaptureTime)
+	}
+	if len(got.Bookmarks) != len(want.Bookmarks) {
+		t.Fatalf("ブックマーク = %+v（期待値 %+v）", got.Bookmarks, want.Bookmarks)
+	}
+	for i := range want.Bookmarks {
+		if got.Bookmarks[i] != want.Bookmarks[i] {
+			t.Errorf("ブックマーク %d = %+v（期待値 %+v）", i, got.Bookmarks[i], want.Bookmarks[i])
+		}
+	}
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	buffer := [][]float32{sineWave(440, 16000, 32000, 0.5)}
+	cues := []CuePoint{{Frame: 8000, Label: "予算の結論"}, {Frame: 12000, Label: "ブックマーク"}}
+
+	paths := map[string]func(path string) error{
+		"pcm16.wav": func(path string) error {
+			_, err := WriteWavFile(path, buffer, 16000, 2, WavOptions{Format: PCM16, Metadata: testMetadata(), Cues: cues})
+			return err
+		},
+		"float.wav": func(path string) error {
+			_, err := WriteWavFile(path, buffer, 16000, 2, WavOptions{Format: Float32, Metadata: testMetadata(), Cues: cues})
+			return err
+		},
+		"pcm24.flac": func(path string) error {
+			_, err := WriteFlacFile(path, buffer, 16000, 2, PCM24, FlacOptions{Metadata: testMetadata(), Cues: cues})
+			return err
+		},
+	}
+	for name, write := range paths {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := write(path); err != nil {
+				t.Fatal(err)
+			}
+
+			// サンプルと一緒に読み込む場合と、形式だけを読み込む場合
+			_, format, err := ReadAudio(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := ReadAudioInfo(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range []WavFormat{format, info} {
+				checkMetadata(t, f.Metadata, testMetadata())
+				if f.Frames != 16000 || len(f.Cues) != len(cues) {
+					t.Fatalf("フレーム数 %d・マーカー %+v", f.Frames, f.Cues)
+				}
+				for i := range cues {
+					if f.Cues[i] != cues[i] {
+						t.Errorf("マーカー %d = %+v（期待値 %+v）", i, f.Cues[i], cues[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMetadataFromBextOnly(t *testing.T) {
+	// LIST/INFOを取り除き、bextのDescriptionだけから読み取る
+	chunks := testMetadata().wavChunks(16000)
+	listSize := 8 + int(binary.LittleEndian.Uint32(chunks[4:8]))
+	bext := chunks[listSize:]
+	if string(bext[0:4]) != "bext" {
+		t.Fatalf("bextチャンクがありません: %q", bext[0:4])
+	}
+
+	wav := riffWav(rawChunk("fmt ", 16, pcm16Format(1, 16000)), bext, rawChunk("data", 4, pcm16Data(1, 2)))
+	_, format, err := DecodeWav(bytes.NewReader(wav))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMetadata(t, format.Metadata, testMetadata())
+	if timeReference := binary.LittleEndian.Uint64(bext[8+338:]); timeReference != uint64(testMetadata().Offset.Seconds()*16000) {
+		t.Errorf("TimeReference = %d", timeReference)
+	}
+}
+
+func TestWavWithoutMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := EncodeWav(&buf, [][]float32{{0, 0.5}}, 16000, 1, WavOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	_, format, err := DecodeWav(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format.Metadata != nil || len(format.Cues) != 0 {
+		t.Errorf("メタデータがないファイルでメタデータが読み込まれました: %+v", format)
+	}
+}
@@ -0,0 +1,131 @@
+package audio
+
+import "math"
+
+// whisper.cppが前提とするサンプリングレート
+const WhisperSampleRate = 16000
+
+const (
+	resampleZeroCrossings = 32   // 片側の零交差数（フィルタ長）
+	resampleTableRes      = 512  // 零交差1つあたりのテーブル分解能
+	resampleRolloff       = 0.95 // ナイキスト周波数に対するカットオフ
+	resampleKaiserBeta    = 8.6  // Kaiser窓の形状パラメータ
+)
+
+// Resamplerは窓関数付きsinc補間でサンプリングレートを変換する
+type Resampler struct {
+	fromRate int
+	toRate   int
+	cutoff   float64   // 入力ナイキストに対する正規化カットオフ
+	table    []float64 // 窓付きsincカーネル（零交差単位でサンプリング）
+}
+
+// 新しいリサンプラーを作成
+func NewResampler(fromRate, toRate int) *Resampler {
+	// ダウンサンプル時は出力ナイキストでエイリアスを除去
+	cutoff := resampleRolloff
+	if toRate < fromRate {
+		cutoff *= float64(toRate) / float64(fromRate)
+	}
+
+	size := resampleZeroCrossings*resampleTableRes + 1
+	table := make([]float64, size+1)
+	i0Beta := besselI0(resampleKaiserBeta)
+	for i := 0; i < size; i++ {
+		u := float64(i) / resampleTableRes
+		x := u / resampleZeroCrossings
+		window := besselI0(resampleKaiserBeta*math.Sqrt(1-x*x)) / i0Beta
+		table[i] = sinc(u) * window
+	}
+
+	return &Resampler{
+		fromRate: fromRate,
+		toRate:   toRate,
+		cutoff:   cutoff,
+		table:    table,
+	}
+}
+
+// Processはサンプル列全体を変換する（範囲外は無音として扱う）
+func (r *Resampler) Process(in []float32) []float32 {
+	if r.fromRate == r.toRate {
+		out := make([]float32, len(in))
+		copy(out, in)
+		return out
+	}
+
+	outLen := int(int64(len(in)) * int64(r.toRate) / int64(r.fromRate))
+	out := make([]float32, outLen)
+
+	step := float64(r.fromRate) / float64(r.toRate)
+	width := resampleZeroCrossings / r.cutoff // 入力サンプル単位の片側幅
+
+	for n := range out {
+		center := float64(n) * step
+		first := int(math.Ceil(center - width))
+		last := int(math.Floor(center + width))
+		if first < 0 {
+			first = 0
+		}
+		if last >= len(in) {
+			last = len(in) - 1
+		}
+
+		var sum float64
+		for i := first; i <= last; i++ {
+			sum += float64(in[i]) * r.kernel(math.Abs(center-float64(i))*r.cutoff)
+		}
+		out[n] = float32(sum * r.cutoff)
+	}
+
+	return out
+}
+
+// テーブルを線形補間してカーネル値を求める
+func (r *Resampler) kernel(u float64) float64 {
+	pos := u * resampleTableRes
+	idx := int(pos)
+	if idx >= len(r.table)-2 {
+		return 0
+	}
+	frac := pos - float64(idx)
+	return r.table[idx] + (r.table[idx+1]-r.table[idx])*frac
+}
+
+// Resampleはサンプル列をfromRateからtoRateに変換する
+func Resample(in []float32, fromRate, toRate int) []float32 {
+	return NewResampler(fromRate, toRate).Process(in)
+}
+
+// Concatはチャンクに分かれたバッファを1つのスライスにまとめる
+func Concat(buffers [][]float32) []float32 {
+	total := 0
+	for _, b := range buffers {
+		total += len(b)
+	}
+	combined := make([]float32, 0, total)
+	for _, b := range buffers {
+		combined = append(combined, b...)
+	}
+	return combined
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// 第1種変形ベッセル関数（0次）の級数展開
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
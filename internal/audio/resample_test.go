@@ -0,0 +1,64 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func sineWave(freq float64, sampleRate int, n int, amplitude float64) []float32 {
+	samples := make([]float32, n)
+	for i := range samples {
+		samples[i] = float32(amplitude * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate)))
+	}
+	return samples
+}
+
+func TestResampleAccuracy(t *testing.T) {
+	tests := []struct {
+		from, to int
+		freq     float64
+	}{
+		{44100, 16000, 1000},
+		{48000, 16000, 3000},
+		{16000, 48000, 440},
+		{22050, 16000, 7000},
+	}
+	for _, tt := range tests {
+		in := sineWave(tt.freq, tt.from, tt.from, 0.5) // 1秒
+		out := Resample(in, tt.from, tt.to)
+		if len(out) != tt.to {
+			t.Errorf("%d→%d Hz: 出力 %d サンプル（期待値 %d）", tt.from, tt.to, len(out), tt.to)
+			continue
+		}
+
+		// 端（フィルタが範囲外の無音を含むところ）を除いて理想的な正弦波と比べる
+		want := sineWave(tt.freq, tt.to, tt.to, 0.5)
+		margin := tt.to / 50
+		var maxErr float64
+		for i := margin; i < len(out)-margin; i++ {
+			maxErr = math.Max(maxErr, math.Abs(float64(out[i]-want[i])))
+		}
+		if maxErr > 1e-3 {
+			t.Errorf("%d→%d Hz・%.0f Hz: 最大誤差 %.5f", tt.from, tt.to, tt.freq, maxErr)
+		}
+	}
+}
+
+func TestResampleAntiAliasing(t *testing.T) {
+	// 16kHzのナイキスト周波数（8kHz）を超える成分は除去される
+	in := sineWave(11000, 44100, 44100, 0.5)
+	out := Resample(in, 44100, 16000)
+	margin := 16000 / 50
+	if rms := RMS(out[margin : len(out)-margin]); rms > 0.001 {
+		t.Errorf("11kHzの成分が残っています: RMS %.5f", rms)
+	}
+}
+
+func TestResampleSameRate(t *testing.T) {
+	in := []float32{0.1, -0.2, 0.3}
+	out := Resample(in, 16000, 16000)
+	out[0] = 1
+	if in[0] != 0.1 {
+		t.Error("同じレートの変換で入力が書き換えられました")
+	}
+}
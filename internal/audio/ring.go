@@ -0,0 +1,68 @@
+package audio
+
+import "sync/atomic"
+
+// RingBufferは単一の書き込み側と単一の読み出し側で共有するロックフリーのリングバッファ
+// 書き込み側（PortAudioのコールバック）はブロックせず、空きがなければ書き込めなかった分を返す
+// 読み書きはフレーム（全チャンネル分のサンプル）単位で行い、チャンネルの並びがずれないようにする
+type RingBuffer struct {
+	data     []float32
+	mask     uint64
+	channels uint64        // 1フレームのサンプル数
+	read     atomic.Uint64 // 読み出し位置（読み出し側のみが更新）
+	write    atomic.Uint64 // 書き込み位置（書き込み側のみが更新）
+}
+
+// 新しいリングバッファを作成（容量はサンプル数で、2のべき乗に切り上げる）
+func NewRingBuffer(capacity int, channels int) *RingBuffer {
+	if channels < 1 {
+		channels = 1
+	}
+	size := 1
+	for size < capacity {
+		size <<= 1
+	}
+	return &RingBuffer{
+		data:     make([]float32, size),
+		mask:     uint64(size - 1),
+		channels: uint64(channels),
+	}
+}
+
+// Writeは書き込める分だけフレーム単位で追加し、書き込んだサンプル数を返す
+// 空きが1フレームに満たない部分や、端数のサンプルは書き込まない
+func (r *RingBuffer) Write(samples []float32) int {
+	w := r.write.Load()
+	free := uint64(len(r.data)) - (w - r.read.Load())
+	n := uint64(len(samples))
+	if n > free {
+		n = free
+	}
+	n -= n % r.channels
+	for i := uint64(0); i < n; i++ {
+		r.data[(w+i)&r.mask] = samples[i]
+	}
+	r.write.Store(w + n)
+	return int(n)
+}
+
+// Readは読み出せる分だけフレーム単位でdstにコピーし、読み出したサンプル数を返す
+func (r *RingBuffer) Read(dst []float32) int {
+	rd := r.read.Load()
+	available := r.write.Load() - rd
+	n := uint64(len(dst))
+	if n > available {
+		n = available
+	}
+	n -= n % r.channels
+	for i := uint64(0); i < n; i++ {
+		dst[i] = r.data[(rd+i)&r.mask]
+	}
+	r.read.Store(rd + n)
+	return int(n)
+}
+
+// Availableは読み出し可能なサンプル数を返す
+func (r *RingBuffer) Available() int {
+	return int(r.write.Load() - r.read.Load())
+}
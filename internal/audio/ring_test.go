@@ -0,0 +1,34 @@
+package audio
+
+import "testing"
+
+func TestRingBufferWholeFrames(t *testing.T) {
+	// 容量8サンプル・3チャンネル：空きは2フレーム（6サンプル）分しか使えない
+	ring := NewRingBuffer(8, 3)
+
+	if n := ring.Write([]float32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}); n != 6 {
+		t.Fatalf("書き込んだサンプル数 = %d（期待値 6）", n)
+	}
+	// 空き2サンプルは1フレームに満たないため書き込まない
+	if n := ring.Write([]float32{13, 14, 15}); n != 0 {
+		t.Fatalf("書き込んだサンプル数 = %d（期待値 0）", n)
+	}
+
+	// 端数のサンプルは読み出さない
+	dst := make([]float32, 5)
+	if n := ring.Read(dst); n != 3 || dst[0] != 1 || dst[2] != 3 {
+		t.Fatalf("読み出し = %d %v", n, dst)
+	}
+
+	// 端数のサンプルを含む書き込みはフレーム単位に切り捨てる
+	if n := ring.Write([]float32{13, 14, 15, 16}); n != 3 {
+		t.Fatalf("書き込んだサンプル数 = %d（期待値 3）", n)
+	}
+	dst = make([]float32, 9)
+	if n := ring.Read(dst); n != 6 || dst[0] != 4 || dst[3] != 13 || dst[5] != 15 {
+		t.Fatalf("読み出し = %d %v", n, dst)
+	}
+	if ring.Available() != 0 {
+		t.Errorf("残り = %d（期待値 0）", ring.Available())
+	}
+}
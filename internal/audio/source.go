@@ -0,0 +1,190 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// AudioSourceは録音ループに音声フレームを供給する入力源
+// Readはインターリーブされたサンプルをbufに書き込み、読み取ったフレーム数を返す
+// 入力が終わった場合はio.EOFを返す
+type AudioSource interface {
+	Open() error
+	Read(buf []float32) (int, error)
+	SampleRate() int
+	Channels() int
+	Close() error
+}
+
+// ErrNoInputは一定時間入力が届かなかったことを示す
+var ErrNoInput = errors.New("入力デバイスからデータが届きません")
+
+// DropReporterは欠落したフレーム数を報告できる入力源
+type DropReporter interface {
+	DroppedFrames() int64
+}
+
+// Recoverableは入力が途絶えた後に開き直せる入力源
+// Recoverはデバイスが使えるようになるかctxがキャンセルされるまでブロックする
+type Recoverable interface {
+	Recover(ctx context.Context) error
+	Name() string
+}
+
+// DeviceInfoは録音デバイスの情報（PortAudioの入力源はaudio/deviceパッケージにある）
+type DeviceInfo struct {
+	Index             int     // デバイス一覧での番号
+	Name              string  // デバイス名
+	MaxInputChannels  int     // 最大入力チャンネル数
+	DefaultSampleRate float64 // デフォルトのサンプリングレート
+}
+
+// FileSourceはWAVファイルを入力源として再生する
+type FileSource struct {
+	Path     string // WAVファイルのパス
+	Realtime bool   // trueの場合は実時間に合わせて読み出す
+
+	samples    []float32
+	channels   int
+	sampleRate int
+	pos        int
+	pacer      pacer
+}
+
+// 新しいファイル入力源を作成
+func NewFileSource(path string, realtime bool) *FileSource {
+	return &FileSource{Path: path, Realtime: realtime}
+}
+
+func (s *FileSource) Open() error {
+	samples, format, err := ReadAudio(s.Path)
+	if err != nil {
+		return err
+	}
+	s.samples = samples
+	s.channels = format.Channels
+	s.sampleRate = format.SampleRate
+	s.pos = 0
+	s.pacer = pacer{enabled: s.Realtime, sampleRate: format.SampleRate}
+	return nil
+}
+
+func (s *FileSource) Read(buf []float32) (int, error) {
+	if s.samples == nil {
+		return 0, fmt.Errorf("ファイル入力源が開かれていません: %s", s.Path)
+	}
+	if s.pos >= len(s.samples) {
+		return 0, io.EOF
+	}
+
+	frames := len(buf) / s.channels
+	remaining := (len(s.samples) - s.pos) / s.channels
+	if frames > remaining {
+		frames = remaining
+	}
+	n := copy(buf[:frames*s.channels], s.samples[s.pos:])
+	s.pos += n
+
+	s.pacer.wait(frames)
+	return frames, nil
+}
+
+func (s *FileSource) SampleRate() int { return s.sampleRate }
+func (s *FileSource) Channels() int   { return s.channels }
+
+func (s *FileSource) Close() error {
+	s.samples = nil
+	return nil
+}
+
+// ToneSourceは指定周波数の正弦波を生成する合成入力源
+type ToneSource struct {
+	Frequency float64       // 周波数（Hz）
+	Amplitude float64       // 振幅（0.0〜1.0）
+	Duration  time.Duration // 生成する長さ（0の場合は無限）
+	Rate      int           // サンプリングレート
+	NumCh     int           // チャンネル数
+	Realtime  bool          // trueの場合は実時間に合わせて生成する
+
+	frame int
+	pacer pacer
+}
+
+// 新しい正弦波入力源を作成
+func NewToneSource(frequency float64, duration time.Duration, sampleRate int, realtime bool) *ToneSource {
+	return &ToneSource{
+		Frequency: frequency,
+		Amplitude: 0.5,
+		Duration:  duration,
+		Rate:      sampleRate,
+		NumCh:     1,
+		Realtime:  realtime,
+	}
+}
+
+func (s *ToneSource) Open() error {
+	if s.Rate <= 0 {
+		return fmt.Errorf("サンプリングレートが不正です: %d", s.Rate)
+	}
+	if s.NumCh <= 0 {
+		s.NumCh = 1
+	}
+	s.frame = 0
+	s.pacer = pacer{enabled: s.Realtime, sampleRate: s.Rate}
+	return nil
+}
+
+func (s *ToneSource) Read(buf []float32) (int, error) {
+	frames := len(buf) / s.NumCh
+	if s.Duration > 0 {
+		total := int(s.Duration.Seconds() * float64(s.Rate))
+		if s.frame >= total {
+			return 0, io.EOF
+		}
+		if frames > total-s.frame {
+			frames = total - s.frame
+		}
+	}
+
+	step := 2 * math.Pi * s.Frequency / float64(s.Rate)
+	for i := 0; i < frames; i++ {
+		v := float32(s.Amplitude * math.Sin(step*float64(s.frame+i)))
+		for c := 0; c < s.NumCh; c++ {
+			buf[i*s.NumCh+c] = v
+		}
+	}
+	s.frame += frames
+
+	s.pacer.wait(frames)
+	return frames, nil
+}
+
+func (s *ToneSource) SampleRate() int { return s.Rate }
+func (s *ToneSource) Channels() int   { return s.NumCh }
+func (s *ToneSource) Close() error    { return nil }
+
+// pacerは読み出し速度を実時間に合わせる
+type pacer struct {
+	enabled    bool
+	sampleRate int
+	start      time.Time
+	frames     int
+}
+
+func (p *pacer) wait(frames int) {
+	if !p.enabled {
+		return
+	}
+	if p.start.IsZero() {
+		p.start = time.Now()
+	}
+	p.frames += frames
+	due := p.start.Add(time.Duration(float64(p.frames) / float64(p.sampleRate) * float64(time.Second)))
+	if d := time.Until(due); d > 0 {
+		time.Sleep(d)
+	}
+}
@@ -0,0 +1,193 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// StreamWavWriterは届いたサンプルを逐次WAVファイルに追記する
+// 一定フレームごとにRIFF/dataのサイズを書き直して同期するため、
+// 強制終了された場合でも直前までの音声がファイルに残る
+type StreamWavWriter struct {
+	SyncEvery int // ヘッダー更新と同期を行う間隔（フレーム数）
+
+	file      *os.File
+	channels  int
+	dataBytes uint32
+	sinceSync int
+	encoder   *WavEncoder
+}
+
+// 新しいストリーミングWAVファイルを作成
+func NewStreamWavWriter(path string, sampleRate int, channels int) (*StreamWavWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("WAVファイルを作成できませんでした: %v", err)
+	}
+
+	// サイズ0のヘッダーを書いておき、追記に合わせて更新する
+	encoder := NewWavEncoder(file, WavOptions{})
+	if err := encoder.WriteHeader(0, sampleRate, channels); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &StreamWavWriter{
+		SyncEvery: sampleRate,
+		file:      file,
+		channels:  channels,
+		encoder:   encoder,
+	}, nil
+}
+
+// Writeはインターリーブされたサンプルを追記する
+func (w *StreamWavWriter) Write(samples []float32) error {
+	if w.file == nil {
+		return fmt.Errorf("WAVファイルは閉じられています")
+	}
+
+	// 範囲外のサンプルは飽和させる（折り返すと大きなノイズになる）
+	if err := w.encoder.WriteSamples(samples); err != nil {
+		return err
+	}
+	w.dataBytes += uint32(len(samples) * 2)
+
+	w.sinceSync += len(samples) / w.channels
+	if w.sinceSync >= w.SyncEvery {
+		return w.Sync()
+	}
+	return nil
+}
+
+// Syncはヘッダーのサイズを書き直してディスクに同期する
+func (w *StreamWavWriter) Sync() error {
+	w.sinceSync = 0
+	if err := writeWavSizes(w.file, 36+w.dataBytes, w.dataBytes); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// Closeはヘッダーを確定してファイルを閉じる
+func (w *StreamWavWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.Sync()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.file = nil
+	return err
+}
+
+// RIFFとdataチャンクのサイズを書き直す（44バイトの標準ヘッダー前提）
+func writeWavSizes(file *os.File, riffSize, dataSize uint32) error {
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], riffSize)
+	if _, err := file.WriteAt(size[:], 4); err != nil {
+		return fmt.Errorf("WAVヘッダー更新エラー: %v", err)
+	}
+	binary.LittleEndian.PutUint32(size[:], dataSize)
+	if _, err := file.WriteAt(size[:], 40); err != nil {
+		return fmt.Errorf("WAVヘッダー更新エラー: %v", err)
+	}
+	return nil
+}
+
+// RepairWavはヘッダーのサイズが実際のファイル長と合わないWAVを修復する
+// 修復した場合はtrueを返す
+func RepairWav(path string) (bool, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return false, fmt.Errorf("WAVファイルを開けませんでした: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	fileSize := info.Size()
+
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(file, header); err != nil || string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return false, fmt.Errorf("WAVファイルではありません: %s", path)
+	}
+
+	// dataチャンクを探す
+	var blockAlign int64 = 1
+	pos := int64(12)
+	for pos+8 <= fileSize {
+		chunk := make([]byte, 8)
+		if _, err := file.ReadAt(chunk, pos); err != nil {
+			return false, fmt.Errorf("WAVチャンク読み込みエラー: %v", err)
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		if id == "fmt " {
+			fmtBody := make([]byte, 16)
+			if _, err := file.ReadAt(fmtBody, pos+8); err == nil {
+				blockAlign = int64(binary.LittleEndian.Uint16(fmtBody[12:14]))
+			}
+		}
+
+		if id == "data" {
+			actual := fileSize - (pos + 8)
+			if blockAlign > 0 {
+				actual -= actual % blockAlign
+			}
+
+			// dataの後ろに別のチャンクが続く正常なファイルはそのまま
+			// （RIFFのサイズがファイル長と合い、後続のチャンクがちょうどファイルの終わりで終わる場合）
+			riffEnd := 8 + int64(binary.LittleEndian.Uint32(header[4:8]))
+			if size < actual && riffEnd >= fileSize && chunksEndAt(file, pos+8+size+size%2, fileSize) {
+				return false, nil
+			}
+
+			// dataチャンクは末尾まで続いているとみなす
+			riffSize := pos + actual
+			if size == actual && int64(binary.LittleEndian.Uint32(header[4:8])) == riffSize {
+				return false, nil
+			}
+
+			var buf [4]byte
+			binary.LittleEndian.PutUint32(buf[:], uint32(riffSize))
+			if _, err := file.WriteAt(buf[:], 4); err != nil {
+				return false, fmt.Errorf("WAVヘッダー修復エラー: %v", err)
+			}
+			binary.LittleEndian.PutUint32(buf[:], uint32(actual))
+			if _, err := file.WriteAt(buf[:], pos+4); err != nil {
+				return false, fmt.Errorf("WAVヘッダー修復エラー: %v", err)
+			}
+			return true, nil
+		}
+
+		pos += 8 + size + size%2
+	}
+
+	return false, fmt.Errorf("dataチャンクが見つかりません: %s", path)
+}
+
+// offsetから並ぶチャンクのヘッダーをたどり、ちょうどendで終わるか
+// 最後のチャンクの埋め草（奇数サイズの後の1バイト）はなくてもよい
+func chunksEndAt(file *os.File, offset int64, end int64) bool {
+	chunk := make([]byte, 8)
+	for offset < end {
+		if offset+8 > end {
+			return false
+		}
+		if _, err := file.ReadAt(chunk, offset); err != nil {
+			return false
+		}
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		offset += 8 + size
+		if offset < end {
+			offset += size % 2
+		}
+	}
+	return offset == end
+}
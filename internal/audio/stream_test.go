@@ -0,0 +1,96 @@
+package audio
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 強制終了を再現する: 最後の同期の後に書き込んだサンプルはヘッダーのサイズに含まれない
+func crashStreamWav(t *testing.T, path string, sampleRate int, channels int, frames int, tail []byte) {
+	t.Helper()
+	w, err := NewStreamWavWriter(path, sampleRate, channels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 録音と同じく少しずつ書き込む
+	for written := 0; written < frames; written += sampleRate / 10 {
+		n := min(sampleRate/10, frames-written)
+		if err := w.Write(make([]float32, n*channels)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := w.file.Write(tail); err != nil {
+		t.Fatal(err)
+	}
+	w.file.Close()
+}
+
+func TestRepairWav(t *testing.T) {
+	dir := t.TempDir()
+	const rate = 8000
+
+	tests := []struct {
+		name   string
+		frames int    // Writeで書き込むフレーム数（SyncEveryごとにヘッダーを更新する）
+		tail   []byte // 同期の後に書き込まれたバイト列
+		want   int    // 修復後のフレーム数
+	}{
+		// 1.5秒分のうち、同期した1秒の後ろの0.5秒がヘッダーに含まれていない
+		{"unsynced_tail", rate * 3 / 2, nil, rate * 3 / 2},
+		// 同期の前に終了した（サイズ0のヘッダーのまま）
+		{"never_synced", rate / 2, nil, rate / 2},
+		// サンプルの途中で書き込みが止まった（端数のバイトは捨てる）
+		{"partial_frame", rate, []byte{1, 2, 3, 4, 5}, rate + 1},
+		// 同期の後ろのサンプルがチャンクIDのように見えても修復する
+		{"ascii_samples", rate, []byte("LIST\x04\x00\x00\x00INFO"), rate + 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".wav")
+			crashStreamWav(t, path, rate, 2, tt.frames, tt.tail)
+
+			repaired, err := RepairWav(path)
+			if err != nil || !repaired {
+				t.Fatalf("修復 %v・エラー %v", repaired, err)
+			}
+			_, format, err := ReadWav(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format.Frames != tt.want || format.Channels != 2 || format.SampleRate != rate {
+				t.Errorf("修復後の形式 %s・%d フレーム（期待値 %d）", format, format.Frames, tt.want)
+			}
+
+			// 修復済みのファイルはそのまま
+			if repaired, err := RepairWav(path); err != nil || repaired {
+				t.Errorf("2回目の修復 %v・エラー %v", repaired, err)
+			}
+		})
+	}
+}
+
+func TestRepairWavKeepsValidFiles(t *testing.T) {
+	dir := t.TempDir()
+	data := pcm16Data(1, 2, 3, 4)
+
+	files := map[string][]byte{
+		"plain":       riffWav(rawChunk("fmt ", 16, pcm16Format(1, 16000)), rawChunk("data", 8, data)),
+		"after_data":  riffWav(rawChunk("fmt ", 16, pcm16Format(1, 16000)), rawChunk("data", 8, data), rawChunk("LIST", 4, []byte("INFO"))),
+		"odd_trailer": riffWav(rawChunk("fmt ", 16, pcm16Format(1, 16000)), rawChunk("data", 8, data), rawChunk("junk", 3, []byte("abc"))),
+	}
+	for name, wav := range files {
+		path := filepath.Join(dir, name+".wav")
+		if err := os.WriteFile(path, wav, 0644); err != nil {
+			t.Fatal(err)
+		}
+		repaired, err := RepairWav(path)
+		if err != nil || repaired {
+			t.Errorf("%s: 修復 %v・エラー %v", name, repaired, err)
+		}
+		if got, _ := os.ReadFile(path); !bytes.Equal(got, wav) {
+			t.Errorf("%s: 正常なファイルが書き換えられました", name)
+		}
+	}
+}
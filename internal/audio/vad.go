@@ -0,0 +1,172 @@
+package audio
+
+import (
+	"math"
+	"time"
+)
+
+// VADConfigは発話区間検出（VAD）による区切りの設定
+type VADConfig struct {
+	FrameDuration   time.Duration // 判定フレームの長さ
+	EnergyThreshold float64       // 発話とみなすRMSの閾値
+	ZCRThreshold    float64       // 摩擦音などを発話とみなすゼロ交差率の閾値
+	MinPause        time.Duration // 区切りとみなす無音の長さ
+	MinSegment      time.Duration // セグメントの最小長
+	MaxSegment      time.Duration // セグメントの最大長（超えたら強制的に区切る）
+}
+
+// デフォルトのVAD設定
+func DefaultVADConfig() VADConfig {
+	return VADConfig{
+		FrameDuration:   30 * time.Millisecond,
+		EnergyThreshold: 0.01,
+		ZCRThreshold:    0.25,
+		MinPause:        600 * time.Millisecond,
+		MinSegment:      5 * time.Second,
+		MaxSegment:      30 * time.Second,
+	}
+}
+
+// Segmenterはモノラル音声を受け取り、発話の切れ目でセグメントの区切り位置を決める
+type Segmenter struct {
+	config     VADConfig
+	frameSize  int
+	minPause   int
+	minSegment int
+	maxSegment int
+
+	pending    []float32 // 判定前の端数サンプル
+	segLen     int       // 現在のセグメントで判定済みのサンプル数
+	silenceRun int       // 末尾の連続無音サンプル数
+}
+
+// 新しいセグメンターを作成
+func NewSegmenter(config VADConfig, sampleRate int) *Segmenter {
+	toSamples := func(d time.Duration) int {
+		return int(d.Seconds() * float64(sampleRate))
+	}
+
+	frameSize := toSamples(config.FrameDuration)
+	if frameSize <= 0 {
+		frameSize = 1
+	}
+
+	return &Segmenter{
+		config:     config,
+		frameSize:  frameSize,
+		minPause:   toSamples(config.MinPause),
+		minSegment: toSamples(config.MinSegment),
+		maxSegment: toSamples(config.MaxSegment),
+	}
+}
+
+// Pushはサンプルを追加し、確定したセグメントの長さ（サンプル数）を順に返す
+// 長さは前回の区切り位置からの相対値
+func (s *Segmenter) Push(samples []float32) []int {
+	var cuts []int
+	s.pending = append(s.pending, samples...)
+
+	for len(s.pending) >= s.frameSize {
+		frame := s.pending[:s.frameSize]
+		s.pending = s.pending[s.frameSize:]
+
+		s.segLen += s.frameSize
+		if s.isSpeech(frame) {
+			s.silenceRun = 0
+		} else {
+			s.silenceRun += s.frameSize
+		}
+
+		switch {
+		case s.segLen >= s.minSegment && s.silenceRun >= s.minPause:
+			// 無音区間の中央で区切り、残りは次のセグメントの先頭にする
+			carry := s.silenceRun / 2
+			cuts = append(cuts, s.segLen-carry)
+			s.segLen = carry
+			s.silenceRun = carry
+		case s.maxSegment > 0 && s.segLen >= s.maxSegment:
+			cuts = append(cuts, s.segLen)
+			s.segLen = 0
+			s.silenceRun = 0
+		}
+	}
+
+	// 端数は再利用されないよう詰め直す
+	s.pending = append([]float32(nil), s.pending...)
+	return cuts
+}
+
+// 区切り状態をリセット（残りのサンプルを保存した後などに使用）
+func (s *Segmenter) Reset() {
+	s.pending = nil
+	s.segLen = 0
+	s.silenceRun = 0
+}
+
+// フレームが発話かどうかを判定
+func (s *Segmenter) isSpeech(frame []float32) bool {
+	rms := RMS(frame)
+	if rms >= s.config.EnergyThreshold {
+		return true
+	}
+	// 弱いが雑音的でない摩擦音（さ行など）を拾う
+	return rms >= s.config.EnergyThreshold/2 && ZeroCrossingRate(frame) >= s.config.ZCRThreshold
+}
+
+// ActiveRatioはRMSがthreshold以上のフレーム（30ms）の割合を返す
+// セグメント全体が無音かどうかの判定に使う
+func ActiveRatio(samples []float32, sampleRate int, threshold float64) float64 {
+	frameSize := sampleRate * 30 / 1000
+	if frameSize <= 0 || len(samples) < frameSize {
+		if RMS(samples) >= threshold {
+			return 1
+		}
+		return 0
+	}
+
+	frames, active := 0, 0
+	for start := 0; start+frameSize <= len(samples); start += frameSize {
+		frames++
+		if RMS(samples[start:start+frameSize]) >= threshold {
+			active++
+		}
+	}
+	return float64(active) / float64(frames)
+}
+
+// RMSは二乗平均平方根を返す
+func RMS(samples []float32) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range samples {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+// Peakは絶対値の最大を返す
+func Peak(samples []float32) float64 {
+	var peak float64
+	for _, v := range samples {
+		if a := math.Abs(float64(v)); a > peak {
+			peak = a
+		}
+	}
+	return peak
+}
+
+// ZeroCrossingRateはサンプル間で符号が変わる割合を返す
+func ZeroCrossingRate(samples []float32) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	crossings := 0
+	for i := 1; i < len(samples); i++ {
+		if (samples[i-1] >= 0) != (samples[i] >= 0) {
+			crossings++
+		}
+	}
+	return float64(crossings) / float64(len(samples)-1)
+}
@@ -0,0 +1,124 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+// 発話（正弦波）と無音を並べた16kHzの音声
+type vadPart struct {
+	speech   bool
+	duration time.Duration
+}
+
+func vadSignal(parts ...vadPart) []float32 {
+	var samples []float32
+	for _, part := range parts {
+		n := int(part.duration.Seconds() * 16000)
+		if part.speech {
+			samples = append(samples, sineWave(200, 16000, n, 0.3)...)
+		} else {
+			samples = append(samples, make([]float32, n)...)
+		}
+	}
+	return samples
+}
+
+// チャンクごとに追加して区切り位置（セグメントの長さ）を集める
+func pushAll(segmenter *Segmenter, samples []float32, chunk int) []int {
+	var cuts []int
+	for pos := 0; pos < len(samples); pos += chunk {
+		cuts = append(cuts, segmenter.Push(samples[pos:min(pos+chunk, len(samples))])...)
+	}
+	return cuts
+}
+
+func TestSegmenterCutPositions(t *testing.T) {
+	// 30msのフレームは480サンプル、600msの無音は9600サンプル
+	config := DefaultVADConfig()
+	const sec = 16000
+
+	tests := []struct {
+		name  string
+		parts []vadPart
+		want  []int
+	}{
+		{
+			// 6秒の発話の後の無音が600msに達したところで、無音の中央（6.3秒）で区切る
+			"pause",
+			[]vadPart{{true, 6 * time.Second}, {false, time.Second}, {true, 3 * time.Second}},
+			[]int{6*sec + 4800},
+		},
+		{
+			// 最小長（5秒）より前の無音では区切らない
+			"pause_before_min",
+			[]vadPart{{true, 2 * time.Second}, {false, time.Second}, {true, 2 * time.Second}},
+			nil,
+		},
+		{
+			// 600ms未満の息継ぎでは区切らない
+			"short_pause",
+			[]vadPart{{true, 6 * time.Second}, {false, 300 * time.Millisecond}, {true, 6 * time.Second}},
+			nil,
+		},
+		{
+			// 無音がなければ最大長（30秒）ごとに区切る
+			"max_segment",
+			[]vadPart{{true, 70 * time.Second}},
+			[]int{30 * sec, 30 * sec},
+		},
+		{
+			// 区切った後の無音（区切り位置から1秒の終わりまでの11200サンプル）は次のセグメントの先頭になる
+			"two_pauses",
+			[]vadPart{{true, 6 * time.Second}, {false, time.Second}, {true, 5 * time.Second}, {false, time.Second}},
+			[]int{6*sec + 4800, 11200 + 5*sec + 4800},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples := vadSignal(tt.parts...)
+			// 一度に追加しても細かく分けて追加しても同じ位置で区切る
+			for _, chunk := range []int{len(samples), 4410, 1000, 333} {
+				cuts := pushAll(NewSegmenter(config, 16000), samples, chunk)
+				if len(cuts) != len(tt.want) {
+					t.Fatalf("チャンク %d: 区切り %v（期待値 %v）", chunk, cuts, tt.want)
+				}
+				for i := range cuts {
+					if cuts[i] != tt.want[i] {
+						t.Fatalf("チャンク %d: 区切り %v（期待値 %v）", chunk, cuts, tt.want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSegmenterFricative(t *testing.T) {
+	// 閾値の半分以上でゼロ交差率が高い弱い音（摩擦音）は発話とみなし、区切らない
+	config := DefaultVADConfig()
+	samples := vadSignal(vadPart{true, 6 * time.Second})
+	for i := 0; i < 16000; i++ {
+		v := float32(0.007)
+		if i%2 == 1 {
+			v = -v
+		}
+		samples = append(samples, v)
+	}
+	samples = append(samples, vadSignal(vadPart{true, time.Second})...)
+
+	if cuts := pushAll(NewSegmenter(config, 16000), samples, 4800); len(cuts) != 0 {
+		t.Errorf("摩擦音で区切られました: %v", cuts)
+	}
+}
+
+func TestSegmenterReset(t *testing.T) {
+	config := DefaultVADConfig()
+	segmenter := NewSegmenter(config, 16000)
+	segmenter.Push(vadSignal(vadPart{true, 20 * time.Second}))
+	segmenter.Reset()
+
+	// リセット後は最大長を数え直す
+	if cuts := segmenter.Push(vadSignal(vadPart{true, 20 * time.Second})); len(cuts) != 0 {
+		t.Errorf("リセット後に区切られました: %v", cuts)
+	}
+}
@@ -0,0 +1,471 @@
+package audio
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"math/cmplx"
+	"os"
+	"time"
+)
+
+// 波形・スペクトログラムの描画設定
+const (
+	visualFFTSize = 1024  // スペクトログラムのFFTの長さ
+	visualFrames  = 8     // 1列あたりに平均するFFTの最大数
+	visualBands   = 128   // 周波数方向の分割数（対数）
+	visualMinFreq = 50.0  // 表示する最低周波数（Hz）
+	visualFloorDB = -90.0 // 表示する最小レベル（dBFS）
+	clipLevel     = 0.999 // クリップとみなす振幅
+)
+
+// Visualizationは波形とスペクトログラムを描画するための列ごとのデータ
+type Visualization struct {
+	Channels int           // 波形のチャンネル数
+	MaxFreq  float64       // スペクトログラムの最高周波数（Hz）
+	Start    time.Duration // 先頭の時刻（セッション開始からの位置）
+	Step     time.Duration // 1列の長さ
+	columns  []visualColumn
+}
+
+// 1列分のピークとスペクトル
+type visualColumn struct {
+	min, max []float32 // チャンネルごとの最小値・最大値
+	clipped  []bool    // チャンネルごとのクリップしたサンプルの有無
+	gap      bool      // 録音されていない区間
+	power    []float64 // 対数周波数の帯域ごとのパワー（0dBFSの正弦波で1）
+}
+
+// 長さ
+func (v *Visualization) Duration() time.Duration {
+	return time.Duration(len(v.columns)) * v.Step
+}
+
+// Analyzeはインターリーブされたサンプルを列数columnsに分けてピークとスペクトルを求める
+func Analyze(samples []float32, channels, sampleRate, columns int) *Visualization {
+	frames := len(samples) / channels
+	columns = max(min(columns, frames), 1)
+	v := &Visualization{
+		Channels: channels,
+		MaxFreq:  float64(sampleRate) / 2,
+		Step:     time.Duration(frames) * time.Second / time.Duration(sampleRate) / time.Duration(columns),
+		columns:  make([]visualColumn, columns),
+	}
+
+	mono := MixToMono(samples, channels)
+	analyzer := newSpectrumAnalyzer(sampleRate, v.MaxFreq)
+	for i := range v.columns {
+		start, end := i*frames/columns, (i+1)*frames/columns
+		column := peakColumn(samples[start*channels:end*channels], channels)
+		column.power = analyzer.analyze(mono, start, end)
+		v.columns[i] = column
+	}
+	return v
+}
+
+// インターリーブされたサンプルのチャンネルごとのピーク
+func peakColumn(samples []float32, channels int) visualColumn {
+	column := visualColumn{
+		min:     make([]float32, channels),
+		max:     make([]float32, channels),
+		clipped: make([]bool, channels),
+	}
+	for i, sample := range samples {
+		c := i % channels
+		column.min[c] = min(column.min[c], sample)
+		column.max[c] = max(column.max[c], sample)
+		if sample >= clipLevel || sample <= -clipLevel {
+			column.clipped[c] = true
+		}
+	}
+	return column
+}
+
+// MixToMonoはインターリーブされたサンプルをモノラルに混合する
+func MixToMono(samples []float32, channels int) []float32 {
+	if channels == 1 {
+		return samples
+	}
+	mono := make([]float32, len(samples)/channels)
+	for i := range mono {
+		var sum float32
+		for _, sample := range samples[i*channels : (i+1)*channels] {
+			sum += sample
+		}
+		mono[i] = sum / float32(channels)
+	}
+	return mono
+}
+
+// spectrumAnalyzerは区間のパワーを対数周波数の帯域ごとに求める
+type spectrumAnalyzer struct {
+	window []float64
+	buf    []complex128
+	bins   [][2]int // 帯域ごとのFFTのビンの範囲（両端を含む）
+}
+
+func newSpectrumAnalyzer(sampleRate int, maxFreq float64) *spectrumAnalyzer {
+	a := &spectrumAnalyzer{
+		window: make([]float64, visualFFTSize),
+		buf:    make([]complex128, visualFFTSize),
+		bins:   make([][2]int, visualBands),
+	}
+	for i := range a.window {
+		a.window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(visualFFTSize))
+	}
+
+	binHz := float64(sampleRate) / visualFFTSize
+	nyquist := visualFFTSize / 2
+	for b := range a.bins {
+		low := visualMinFreq * math.Pow(maxFreq/visualMinFreq, float64(b)/visualBands)
+		high := visualMinFreq * math.Pow(maxFreq/visualMinFreq, float64(b+1)/visualBands)
+		first := min(int(math.Round(low/binHz)), nyquist)
+		last := min(max(int(math.Round(high/binHz))-1, first), nyquist)
+		a.bins[b] = [2]int{first, last}
+	}
+	return a
+}
+
+// mono[start:end]の区間に均等にFFTの窓を置き、帯域ごとのパワーの平均を求める
+func (a *spectrumAnalyzer) analyze(mono []float32, start, end int) []float64 {
+	power := make([]float64, visualBands)
+	frames := min(max((end-start)/visualFFTSize, 1), visualFrames)
+
+	// 0dBFSの正弦波のパワー（ハン窓の係数の和はN/2、振幅はその半分）
+	reference := math.Pow(visualFFTSize/4, 2)
+	for f := 0; f < frames; f++ {
+		center := start + (2*f+1)*(end-start)/(2*frames)
+		offset := center - visualFFTSize/2
+		for i := range a.buf {
+			var sample float32
+			if j := offset + i; j >= 0 && j < len(mono) {
+				sample = mono[j]
+			}
+			a.buf[i] = complex(float64(sample)*a.window[i], 0)
+		}
+		fft(a.buf)
+
+		for b, bins := range a.bins {
+			peak := 0.0
+			for k := bins[0]; k <= bins[1]; k++ {
+				peak = max(peak, real(a.buf[k])*real(a.buf[k])+imag(a.buf[k])*imag(a.buf[k]))
+			}
+			power[b] += peak / reference / float64(frames)
+		}
+	}
+	return power
+}
+
+// 長さが2のべき乗のデータを高速フーリエ変換する（その場で置き換える）
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := x[start+k], x[start+k+size/2]*w
+				x[start+k] = even + odd
+				x[start+k+size/2] = even - odd
+				w *= step
+			}
+		}
+	}
+}
+
+// Overviewはセッション全体の粗い波形とスペクトログラムを一定の間隔ごとに蓄積する
+// 音声は蓄積せず、列ごとのピークとスペクトルだけを保持する（モノラル）
+type Overview struct {
+	Step       time.Duration // 1列の長さ
+	sampleRate int
+	maxFreq    float64
+	analyzer   *spectrumAnalyzer
+	pending    []float32 // 1列に満たないサンプル
+	columns    []visualColumn
+}
+
+// 新しい概要を作成
+func NewOverview(step time.Duration) *Overview {
+	return &Overview{Step: step}
+}
+
+// これまでに追加した長さ
+func (o *Overview) Duration() time.Duration {
+	d := time.Duration(len(o.columns)) * o.Step
+	if o.sampleRate > 0 {
+		d += time.Duration(len(o.pending)) * time.Second / time.Duration(o.sampleRate)
+	}
+	return d
+}
+
+// Addはインターリーブされたサンプルを追加する
+func (o *Overview) Add(samples []float32, channels, sampleRate int) {
+	if o.maxFreq == 0 {
+		o.maxFreq = float64(sampleRate) / 2
+	}
+	if sampleRate != o.sampleRate {
+		o.flush()
+		o.sampleRate = sampleRate
+		o.analyzer = newSpectrumAnalyzer(sampleRate, o.maxFreq)
+	}
+
+	o.pending = append(o.pending, MixToMono(samples, channels)...)
+	size := int(o.Step * time.Duration(sampleRate) / time.Second)
+	for len(o.pending) >= size {
+		o.addColumn(o.pending[:size])
+		o.pending = o.pending[size:]
+	}
+	o.pending = append([]float32(nil), o.pending...)
+}
+
+// AddGapは録音されていない区間を追加する
+func (o *Overview) AddGap(d time.Duration) {
+	o.flush()
+	for n := int(d / o.Step); n > 0; n-- {
+		o.columns = append(o.columns, visualColumn{min: []float32{0}, max: []float32{0}, clipped: []bool{false}, gap: true})
+	}
+}
+
+// 1列に満たないサンプルを1列として追加する
+func (o *Overview) flush() {
+	if len(o.pending) > 0 {
+		o.addColumn(o.pending)
+		o.pending = nil
+	}
+}
+
+func (o *Overview) addColumn(mono []float32) {
+	column := peakColumn(mono, 1)
+	column.power = o.analyzer.analyze(mono, 0, len(mono))
+	o.columns = append(o.columns, column)
+}
+
+// Visualizationは蓄積した内容を描画用のデータとして返す
+func (o *Overview) Visualization() *Visualization {
+	o.flush()
+	return &Visualization{Channels: 1, MaxFreq: o.maxFreq, Step: o.Step, columns: o.columns}
+}
+
+// 描画する幅に合わせた列（列が多い場合はまとめ、少ない場合は引き伸ばす）
+func (v *Visualization) resample(width int) []visualColumn {
+	out := make([]visualColumn, width)
+	n := len(v.columns)
+	for x := range out {
+		first := x * n / width
+		last := max((x+1)*n/width, first+1)
+		merged := visualColumn{
+			min:     make([]float32, v.Channels),
+			max:     make([]float32, v.Channels),
+			clipped: make([]bool, v.Channels),
+			gap:     true,
+			power:   make([]float64, visualBands),
+		}
+		for _, column := range v.columns[first:min(last, n)] {
+			for c := range merged.min {
+				if c < len(column.min) {
+					merged.min[c] = min(merged.min[c], column.min[c])
+					merged.max[c] = max(merged.max[c], column.max[c])
+					merged.clipped[c] = merged.clipped[c] || column.clipped[c]
+				}
+			}
+			merged.gap = merged.gap && column.gap
+			for b, p := range column.power {
+				merged.power[b] = max(merged.power[b], p)
+			}
+		}
+		out[x] = merged
+	}
+	return out
+}
+
+// WriteWaveformSVGは波形をSVGとして書き込む
+// チャンネルごとに段を分け、クリップした列を赤、録音されていない区間を灰色で示す
+func (v *Visualization) WriteWaveformSVG(w io.Writer, width, height int) error {
+	const axisHeight = 16 // 時刻を表示する領域
+	columns := v.resample(width)
+	laneHeight := float64(height-axisHeight) / float64(v.Channels)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="10">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", width, height)
+
+	// 録音されていない区間
+	for x := 0; x < width; {
+		if !columns[x].gap {
+			x++
+			continue
+		}
+		end := x
+		for end < width && columns[end].gap {
+			end++
+		}
+		fmt.Fprintf(bw, `<rect x="%d" y="0" width="%d" height="%d" fill="#e0e0e0"/>`+"\n", x, end-x, height-axisHeight)
+		x = end
+	}
+
+	for c := 0; c < v.Channels; c++ {
+		top := float64(c) * laneHeight
+		center := top + laneHeight/2
+		fmt.Fprintf(bw, `<line x1="0" y1="%.1f" x2="%d" y2="%.1f" stroke="#cccccc"/>`+"\n", center, width, center)
+
+		// 列ごとの最小値から最大値までの縦線（クリップした列は別の色で描く）
+		for _, clipped := range []bool{false, true} {
+			color := "#1f77b4"
+			if clipped {
+				color = "#d62728"
+			}
+			fmt.Fprintf(bw, `<path stroke="%s" fill="none" d="`, color)
+			for x, column := range columns {
+				if column.gap || column.clipped[c] != clipped {
+					continue
+				}
+				y1 := center - float64(min(max(column.max[c], -1), 1))*laneHeight/2
+				y2 := center - float64(min(max(column.min[c], -1), 1))*laneHeight/2
+				if y2-y1 < 1 {
+					y1, y2 = y1-0.5, y1+0.5
+				}
+				fmt.Fprintf(bw, "M%d.5 %.1fV%.1f", x, y1, y2)
+			}
+			fmt.Fprintf(bw, `"/>`+"\n")
+		}
+		if v.Channels > 1 {
+			fmt.Fprintf(bw, `<text x="4" y="%.1f" fill="#555555">ch%d</text>`+"\n", top+12, c+1)
+		}
+	}
+
+	// 時刻の目盛り（セッション開始からの経過時刻）
+	axis := height - axisHeight
+	fmt.Fprintf(bw, `<line x1="0" y1="%d" x2="%d" y2="%d" stroke="#999999"/>`+"\n", axis, width, axis)
+	duration := v.Duration()
+	if duration > 0 {
+		interval := tickInterval(duration, width/100)
+		for t := (v.Start + interval - 1) / interval * interval; t <= v.Start+duration; t += interval {
+			x := float64(t-v.Start) / float64(duration) * float64(width)
+			anchor := "middle"
+			if x < 30 {
+				anchor = "start"
+			} else if x > float64(width)-30 {
+				anchor = "end"
+			}
+			fmt.Fprintf(bw, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#999999"/>`, x, axis, x, axis+3)
+			fmt.Fprintf(bw, `<text x="%.1f" y="%d" fill="#555555" text-anchor="%s">%s</text>`+"\n", x, height-3, anchor, formatClock(t))
+		}
+	}
+
+	fmt.Fprintf(bw, "</svg>\n")
+	return bw.Flush()
+}
+
+// 目盛りの数がcount以下になるきりのよい間隔
+func tickInterval(duration time.Duration, count int) time.Duration {
+	for _, seconds := range []int{1, 2, 5, 10, 15, 30, 60, 120, 300, 600, 900, 1800, 3600} {
+		interval := time.Duration(seconds) * time.Second
+		if duration/interval <= time.Duration(max(count, 1)) {
+			return interval
+		}
+	}
+	return 2 * time.Hour
+}
+
+// HH:MM:SS 形式の時刻
+func formatClock(d time.Duration) string {
+	seconds := int(d / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// SpectrogramImageは縦軸を対数周波数としたスペクトログラムを描画する
+// 録音されていない区間は灰色、100Hz・1kHz・10kHzの位置に点線を入れる
+func (v *Visualization) SpectrogramImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	columns := v.resample(width)
+
+	// 目盛りの行
+	guides := make(map[int]bool)
+	for _, freq := range []float64{100, 1000, 10000} {
+		if freq < v.MaxFreq {
+			position := math.Log(freq/visualMinFreq) / math.Log(v.MaxFreq/visualMinFreq)
+			guides[height-1-int(position*float64(height))] = true
+		}
+	}
+
+	for x, column := range columns {
+		for y := 0; y < height; y++ {
+			var c color.RGBA
+			if column.gap {
+				c = color.RGBA{90, 90, 90, 255}
+			} else {
+				band := (height - 1 - y) * visualBands / height
+				db := 10 * math.Log10(max(column.power[band], 1e-12))
+				c = heatColor((db - visualFloorDB) / -visualFloorDB)
+			}
+			if guides[y] && x%4 < 2 {
+				c = color.RGBA{c.R/2 + 127, c.G/2 + 127, c.B/2 + 127, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// 0〜1の値を黒→紫→赤→橙→黄の色に変換する
+func heatColor(value float64) color.RGBA {
+	stops := [...][3]float64{
+		{0, 0, 4},
+		{87, 16, 110},
+		{188, 55, 84},
+		{249, 142, 9},
+		{252, 255, 164},
+	}
+	value = min(max(value, 0), 1) * float64(len(stops)-1)
+	i := min(int(value), len(stops)-2)
+	t := value - float64(i)
+	mix := func(k int) uint8 { return uint8(stops[i][k] + (stops[i+1][k]-stops[i][k])*t) }
+	return color.RGBA{mix(0), mix(1), mix(2), 255}
+}
+
+// SaveWaveformSVGは波形をSVGファイルとして保存する
+func SaveWaveformSVG(path string, v *Visualization, width, height int) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("波形の画像を作成できませんでした: %v", err)
+	}
+	err = v.WriteWaveformSVG(file, width, height)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("波形の画像を保存できませんでした: %v", err)
+	}
+	return nil
+}
+
+// SaveSpectrogramPNGはスペクトログラムをPNGファイルとして保存する
+func SaveSpectrogramPNG(path string, v *Visualization, width, height int) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("スペクトログラムの画像を作成できませんでした: %v", err)
+	}
+	err = png.Encode(file, v.SpectrogramImage(width, height))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("スペクトログラムの画像を保存できませんでした: %v", err)
+	}
+	return nil
+}
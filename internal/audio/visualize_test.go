@@ -0,0 +1,144 @@
+package audio
+
+import (
+	"encoding/xml"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 1列目が正弦波、2列目がクリップした矩形波の2チャンネルの音声
+func clippedStereo(n int) []float32 {
+	left := sineWave(440, 16000, n, 0.5)
+	samples := make([]float32, 0, 2*n)
+	for i := range left {
+		right := float32(1)
+		if i/100%2 == 1 {
+			right = -1
+		}
+		samples = append(samples, left[i], right)
+	}
+	return samples
+}
+
+func TestWaveformSVG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "waveform.svg")
+	v := Analyze(clippedStereo(16000), 2, 16000, 400)
+	if err := SaveWaveformSVG(path, v, 400, 120); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// XMLとして読めること、大きさ、チャンネルごとの段、クリップした列の色を確かめる
+	var texts []string
+	paths := make(map[string]string) // 色ごとの描画内容
+	decoder := xml.NewDecoder(file)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("SVGを解析できません: %v", err)
+		}
+		switch e := token.(type) {
+		case xml.StartElement:
+			attrs := make(map[string]string)
+			for _, attr := range e.Attr {
+				attrs[attr.Name.Local] = attr.Value
+			}
+			switch e.Name.Local {
+			case "svg":
+				if attrs["width"] != "400" || attrs["height"] != "120" {
+					t.Errorf("SVGの大きさ %s×%s（期待値 400×120）", attrs["width"], attrs["height"])
+				}
+			case "path":
+				paths[attrs["stroke"]] += attrs["d"]
+			}
+		case xml.CharData:
+			if text := strings.TrimSpace(string(e)); text != "" {
+				texts = append(texts, text)
+			}
+		}
+	}
+
+	if !strings.Contains(paths["#1f77b4"], "M") || !strings.Contains(paths["#d62728"], "M") {
+		t.Errorf("波形またはクリップした列が描かれていません: %v", paths)
+	}
+	for _, want := range []string{"ch1", "ch2", "00:00:00", "00:00:01"} {
+		if !strings.Contains(strings.Join(texts, " "), want) {
+			t.Errorf("SVGに %q がありません: %v", want, texts)
+		}
+	}
+}
+
+// 画素の明るさ（heatColorは値が大きいほど明るい）
+func brightness(c color.Color) int {
+	r, g, b, _ := c.RGBA()
+	return int(r>>8 + g>>8 + b>>8)
+}
+
+func TestSpectrogramPeak(t *testing.T) {
+	const width, height = 200, 128
+	path := filepath.Join(t.TempDir(), "spectrogram.png")
+	v := Analyze(sineWave(1000, 16000, 32000, 0.5), 1, 16000, 100)
+	if err := SaveSpectrogramPNG(path, v, width, height); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != width || bounds.Dy() != height {
+		t.Fatalf("画像の大きさ %v（期待値 %d×%d）", bounds, width, height)
+	}
+
+	// 1kHzの行（下から対数周波数で50Hz〜8kHz）が最も明るい（目盛りの点線がない列で確かめる）
+	position := math.Log(1000/visualMinFreq) / math.Log(8000/visualMinFreq)
+	want := height - 1 - int(position*height)
+	for x := 3; x < width; x += 40 {
+		peak := 0
+		for y := range height {
+			if brightness(img.At(x, y)) > brightness(img.At(x, peak)) {
+				peak = y
+			}
+		}
+		if peak < want-1 || peak > want+1 {
+			t.Errorf("列 %d: 最も明るい行 %d（期待値 %d）", x, peak, want)
+		}
+	}
+}
+
+func TestOverviewGap(t *testing.T) {
+	overview := NewOverview(100 * time.Millisecond)
+	overview.Add(sineWave(1000, 16000, 16000, 0.5), 1, 16000)
+	overview.AddGap(time.Second)
+	overview.Add(sineWave(1000, 16000, 16000, 0.5), 1, 16000)
+	if d := overview.Duration(); d != 3*time.Second {
+		t.Errorf("長さ %v（期待値 3秒）", d)
+	}
+
+	// 録音されていない区間は灰色で描く
+	img := overview.Visualization().SpectrogramImage(30, 64)
+	gray := color.RGBA{90, 90, 90, 255}
+	for x, want := range map[int]bool{5: false, 15: true, 25: false} {
+		if got := img.RGBAAt(x, 0) == gray; got != want {
+			t.Errorf("列 %d が録音なし: %v（期待値 %v）", x, got, want)
+		}
+	}
+}
@@ -3,77 +3,180 @@ package audio
 import (
 	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
 )
 
-// WAVファイルとして保存
+// 1回の変換・書き込みで扱うサンプル数
+const wavBlockSamples = 16384
+
+// WAVファイルとして保存（モノラル）
 func SaveAsWav(filepath string, audioBuffer [][]float32, sampleRate int) error {
+	return SaveAsWavChannels(filepath, audioBuffer, sampleRate, 1)
+}
+
+// インターリーブされたバッファを複数チャンネルのWAVファイルとして保存（16bit PCM）
+func SaveAsWavChannels(filepath string, audioBuffer [][]float32, sampleRate int, channels int) error {
+	_, err := WriteWavFile(filepath, audioBuffer, sampleRate, channels, WavOptions{})
+	return err
+}
+
+// WriteWavFileはインターリーブされたバッファを指定の形式でWAVファイルに保存する
+// 整数PCMでは-1.0〜1.0を超えるサンプルを飽和させ、その数を返す
+func WriteWavFile(filepath string, audioBuffer [][]float32, sampleRate int, channels int, options WavOptions) (int64, error) {
 	if len(audioBuffer) == 0 {
-		return fmt.Errorf("保存するオーディオバッファが空です")
+		return 0, fmt.Errorf("保存するオーディオバッファが空です")
+	}
+
+	// ファイルを作成
+	file, err := os.Create(filepath)
+	if err != nil {
+		return 0, fmt.Errorf("WAVファイルを作成できませんでした: %v", err)
 	}
 
-	// バッファのサイズを計算
+	clipped, err := EncodeWav(file, audioBuffer, sampleRate, channels, options)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("WAVファイルを保存できませんでした: %v", closeErr)
+	}
+	return clipped, err
+}
+
+// EncodeWavはインターリーブされたバッファをWAV形式でwに書き込み、飽和させたサンプル数を返す
+// チャンクを結合せず、ブロックごとに変換して書き込む
+func EncodeWav(w io.Writer, audioBuffer [][]float32, sampleRate int, channels int, options WavOptions) (int64, error) {
 	totalSamples := 0
 	for _, buffer := range audioBuffer {
 		totalSamples += len(buffer)
 	}
 
-	// バッファを1つの配列に結合
-	combined := make([]float32, 0, totalSamples)
+	encoder := NewWavEncoder(w, options)
+	if err := encoder.WriteHeader(totalSamples, sampleRate, channels); err != nil {
+		return 0, err
+	}
 	for _, buffer := range audioBuffer {
-		combined = append(combined, buffer...)
+		if err := encoder.WriteSamples(buffer); err != nil {
+			return encoder.Clipped(), err
+		}
 	}
+	return encoder.Clipped(), nil
+}
 
-	// float32からint16に変換（WAVファイル形式用）
-	samples := make([]int16, len(combined))
-	for i, sample := range combined {
-		// float32（-1.0〜1.0）からint16（-32768〜32767）への変換
-		samples[i] = int16(sample * 32767.0)
-	}
+// WavEncoderはfloat32のサンプルをWAVのバイト列に変換してio.Writerに書き込む
+// 変換は一定のサンプル数ごとに行い、変換用のバッファは使い回す
+type WavEncoder struct {
+	w       io.Writer
+	encoder *sampleEncoder
+	buf     []byte
+	mono    []float32 // チャンネルを取り出すためのバッファ
+}
 
-	// ファイルを作成
-	file, err := os.Create(filepath)
-	if err != nil {
-		return fmt.Errorf("WAVファイルを作成できませんでした: %v", err)
+// 新しいエンコーダーを作成
+func NewWavEncoder(w io.Writer, options WavOptions) *WavEncoder {
+	encoder := newSampleEncoder(options)
+	return &WavEncoder{
+		w:       w,
+		encoder: encoder,
+		buf:     make([]byte, 0, wavBlockSamples*encoder.bytesPerSample()),
 	}
-	defer file.Close()
+}
 
-	// WAVヘッダーを書き込む
-	writeWavHeader(file, uint32(totalSamples), 1, uint32(sampleRate), 16)
+// WriteHeaderはdataチャンクのサンプル数を指定してヘッダーとメタデータを書き込む
+func (e *WavEncoder) WriteHeader(samples int, sampleRate int, channels int) error {
+	var chunks []byte
+	if e.encoder.options.Metadata != nil {
+		chunks = e.encoder.options.Metadata.wavChunks(sampleRate)
+	}
+	chunks = append(chunks, cueChunks(e.encoder.options.Cues)...)
+	format := e.encoder.options.Format
+	header := wavHeader(uint32(samples), uint16(channels), uint32(sampleRate), uint16(format.BitsPerSample()), format.formatTag(), chunks)
+	if _, err := e.w.Write(header); err != nil {
+		return fmt.Errorf("WAVヘッダー書き込みエラー: %v", err)
+	}
+	return nil
+}
 
-	// サンプルデータを書き込む
-	for _, sample := range samples {
-		err := binary.Write(file, binary.LittleEndian, sample)
-		if err != nil {
+// WriteSamplesはサンプルをブロックごとに変換して書き込む
+func (e *WavEncoder) WriteSamples(samples []float32) error {
+	for len(samples) > 0 {
+		n := min(len(samples), wavBlockSamples)
+		e.buf = e.encoder.encode(e.buf[:0], samples[:n])
+		if _, err := e.w.Write(e.buf); err != nil {
 			return fmt.Errorf("サンプルデータ書き込みエラー: %v", err)
 		}
+		samples = samples[n:]
 	}
-	
 	return nil
 }
 
-// WAVヘッダーを書き込む関数
-func writeWavHeader(file *os.File, dataSize uint32, numChannels uint16, sampleRate uint32, bitsPerSample uint16) {
+// WriteChannelはインターリーブされたバッファからチャンネルcのサンプルだけを書き込む
+// チャンクの境界がフレームの途中にあってもよい
+func (e *WavEncoder) WriteChannel(audioBuffer [][]float32, channels int, c int) error {
+	if cap(e.mono) < wavBlockSamples {
+		e.mono = make([]float32, 0, wavBlockSamples)
+	}
+	block := e.mono[:0]
+	index := 0 // バッファ全体での位置
+	for _, buffer := range audioBuffer {
+		// このチャンク内で最初にチャンネルcのサンプルがある位置
+		first := ((c-index)%channels + channels) % channels
+		for i := first; i < len(buffer); i += channels {
+			block = append(block, buffer[i])
+			if len(block) == cap(block) {
+				if err := e.WriteSamples(block); err != nil {
+					return err
+				}
+				block = block[:0]
+			}
+		}
+		index += len(buffer)
+	}
+	return e.WriteSamples(block)
+}
+
+// これまでに飽和させたサンプル数
+func (e *WavEncoder) Clipped() int64 {
+	return e.encoder.Clipped
+}
+
+// WAVヘッダー（chunksはfmtとdataの間に入れるチャンク）
+// 整数PCM以外（浮動小数点）はcbSizeを含む18バイトのfmtチャンクと、フレーム数を示すfactチャンクを書き込む
+func wavHeader(dataSize uint32, numChannels uint16, sampleRate uint32, bitsPerSample uint16, formatTag uint16, chunks []byte) []byte {
 	// 計算値
 	bytesPerSample := bitsPerSample / 8
 	dataBytes := dataSize * uint32(bytesPerSample)
+	nonPCM := formatTag != wavFormatPCM
+	fmtSize := uint32(16)
+	if nonPCM {
+		fmtSize = 18
+		fact := appendChunk(nil, "fact", binary.LittleEndian.AppendUint32(nil, dataSize/uint32(numChannels)))
+		chunks = append(fact, chunks...)
+	}
+
+	header := make([]byte, 0, 28+int(fmtSize)+len(chunks))
 
 	// RIFFヘッダー
-	file.WriteString("RIFF")
-	binary.Write(file, binary.LittleEndian, uint32(36+dataBytes))
-	file.WriteString("WAVE")
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, 4+8+fmtSize+uint32(len(chunks))+8+dataBytes)
+	header = append(header, "WAVE"...)
 
 	// fmtチャンク
-	file.WriteString("fmt ")
-	binary.Write(file, binary.LittleEndian, uint32(16)) // fmtチャンクのサイズ
-	binary.Write(file, binary.LittleEndian, uint16(1))  // フォーマットタグ（PCM = 1）
-	binary.Write(file, binary.LittleEndian, numChannels)
-	binary.Write(file, binary.LittleEndian, sampleRate)
-	binary.Write(file, binary.LittleEndian, uint32(sampleRate*uint32(numChannels)*uint32(bytesPerSample))) // バイト/秒
-	binary.Write(file, binary.LittleEndian, uint16(numChannels*bytesPerSample))                           // ブロックサイズ
-	binary.Write(file, binary.LittleEndian, bitsPerSample)
+	header = append(header, "fmt "...)
+	header = binary.LittleEndian.AppendUint32(header, fmtSize) // fmtチャンクのサイズ
+	header = binary.LittleEndian.AppendUint16(header, formatTag)
+	header = binary.LittleEndian.AppendUint16(header, numChannels)
+	header = binary.LittleEndian.AppendUint32(header, sampleRate)
+	header = binary.LittleEndian.AppendUint32(header, sampleRate*uint32(numChannels)*uint32(bytesPerSample)) // バイト/秒
+	header = binary.LittleEndian.AppendUint16(header, numChannels*bytesPerSample)                            // ブロックサイズ
+	header = binary.LittleEndian.AppendUint16(header, bitsPerSample)
+	if nonPCM {
+		header = binary.LittleEndian.AppendUint16(header, 0) // cbSize（拡張情報なし）
+	}
+
+	// factやメタデータなどのチャンク
+	header = append(header, chunks...)
 
 	// dataチャンク
-	file.WriteString("data")
-	binary.Write(file, binary.LittleEndian, dataBytes)
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, dataBytes)
+	return header
 }
@@ -0,0 +1,123 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestWavHeaderLayout(t *testing.T) {
+	buffer := [][]float32{make([]float32, 300)}
+
+	tests := []struct {
+		format  SampleFormat
+		fmtSize uint32
+		fact    bool
+	}{
+		{PCM16, 16, false},
+		{PCM24, 16, false},
+		{Float32, 18, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			if _, err := EncodeWav(&buf, buffer, 48000, 3, WavOptions{Format: tt.format}); err != nil {
+				t.Fatal(err)
+			}
+			data := buf.Bytes()
+
+			if size := binary.LittleEndian.Uint32(data[4:8]); int(size) != len(data)-8 {
+				t.Errorf("RIFFのサイズ = %d（期待値 %d）", size, len(data)-8)
+			}
+			if size := binary.LittleEndian.Uint32(data[16:20]); size != tt.fmtSize {
+				t.Fatalf("fmtチャンクのサイズ = %d（期待値 %d）", size, tt.fmtSize)
+			}
+
+			next := 20 + int(tt.fmtSize)
+			if tt.fact {
+				if cbSize := binary.LittleEndian.Uint16(data[36:38]); cbSize != 0 {
+					t.Errorf("cbSize = %d（期待値 0）", cbSize)
+				}
+				if string(data[next:next+4]) != "fact" || binary.LittleEndian.Uint32(data[next+4:]) != 4 {
+					t.Fatalf("factチャンクがありません: %q", data[next:next+8])
+				}
+				if frames := binary.LittleEndian.Uint32(data[next+8:]); frames != 100 {
+					t.Errorf("factのフレーム数 = %d（期待値 100）", frames)
+				}
+				next += 12
+			}
+			if string(data[next:next+4]) != "data" {
+				t.Fatalf("dataチャンクの位置が違います: %q", data[next:next+4])
+			}
+			if size := binary.LittleEndian.Uint32(data[next+4:]); int(size) != 300*tt.format.BitsPerSample()/8 {
+				t.Errorf("dataのサイズ = %d", size)
+			}
+		})
+	}
+}
+
+func TestFloatWavInfo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "float.wav")
+	buffer := [][]float32{make([]float32, 4800)}
+	options := WavOptions{Format: Float32, Metadata: &WavMetadata{SessionID: "20250101_120000", Sequence: 3}}
+	if _, err := WriteWavFile(path, buffer, 48000, 2, options); err != nil {
+		t.Fatal(err)
+	}
+
+	format, err := ReadAudioInfo(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !format.Float || format.Frames != 2400 || format.Metadata == nil || format.Metadata.Sequence != 3 {
+		t.Errorf("形式 = %+v", format)
+	}
+}
+
+func TestWriteChannelUnevenChunks(t *testing.T) {
+	// 3チャンネル・40000フレーム（モノラルの変換ブロックを2回またぐ）。範囲外のサンプルも含める
+	const channels, frames = 3, 40000
+	rng := rand.New(rand.NewSource(1))
+	whole := make([]float32, channels*frames)
+	for i := range whole {
+		whole[i] = rng.Float32()*2.2 - 1.1
+	}
+
+	// チャンネル数の倍数でない長さのチャンクに分ける（境界がフレームの途中になる）
+	var chunks [][]float32
+	for pos, i := 0, 0; pos < len(whole); i++ {
+		n := min([]int{1, 7, 4999, wavBlockSamples + 1, 2}[i%5], len(whole)-pos)
+		chunks = append(chunks, whole[pos:pos+n])
+		pos += n
+	}
+
+	for _, format := range []SampleFormat{PCM16, PCM24, Float32} {
+		for c := 0; c < channels; c++ {
+			mono := make([]float32, frames)
+			for i := range mono {
+				mono[i] = whole[i*channels+c]
+			}
+			var want bytes.Buffer
+			wantClipped, err := EncodeWav(&want, [][]float32{mono}, 16000, 1, WavOptions{Format: format})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got bytes.Buffer
+			encoder := NewWavEncoder(&got, WavOptions{Format: format})
+			if err := encoder.WriteHeader(frames, 16000, 1); err != nil {
+				t.Fatal(err)
+			}
+			if err := encoder.WriteChannel(chunks, channels, c); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Errorf("%s・チャンネル %d: 全体を変換した場合とバイト列が違います（%d バイト、期待値 %d バイト）", format, c, got.Len(), want.Len())
+			}
+			if encoder.Clipped() != wantClipped {
+				t.Errorf("%s・チャンネル %d: クリップ数 %d（期待値 %d）", format, c, encoder.Clipped(), wantClipped)
+			}
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// WAVのフォーマットタグ
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatFloat      = 0x0003
+	wavFormatExtensible = 0xFFFE
+)
+
+// WavFormatはWAVファイルの形式
+type WavFormat struct {
+	Float         bool // IEEE浮動小数点（falseの場合は整数PCM）
+	Channels      int  // チャンネル数
+	SampleRate    int  // サンプリングレート
+	BitsPerSample int  // 1サンプルのビット数
+	Frames        int  // フレーム数（1フレーム = 全チャンネルの1サンプル）
+
+	Metadata *WavMetadata // 録音セッションのメタデータ（ない場合はnil）
+	Cues     []CuePoint   // cue/lablチャンクのマーカー
+}
+
+// 再生時間
+func (f WavFormat) Duration() time.Duration {
+	if f.SampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(int64(f.Frames) * int64(time.Second) / int64(f.SampleRate))
+}
+
+// 形式の説明（例: 16bit PCM / 2ch / 44100 Hz / 30.0秒）
+func (f WavFormat) String() string {
+	encoding := "PCM"
+	if f.Float {
+		encoding = "float"
+	}
+	return fmt.Sprintf("%dbit %s / %dch / %d Hz / %.1f秒",
+		f.BitsPerSample, encoding, f.Channels, f.SampleRate, f.Duration().Seconds())
+}
+
+// ReadWavはWAVファイルを読み込み、インターリーブされたサンプル（-1.0〜1.0）と形式を返す
+// 8/16/24/32bit の整数PCMと32/64bitの浮動小数点に対応する
+// dataより前のLIST/INFO・bextチャンクにセッションのメタデータがあればformat.Metadataに、
+// cue/lablチャンクのマーカーはformat.Cuesに入れる
+func ReadWav(path string) ([]float32, WavFormat, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, WavFormat{}, fmt.Errorf("WAVファイルを読み込めませんでした: %v", err)
+	}
+	defer file.Close()
+
+	samples, format, err := DecodeWav(bufio.NewReader(file))
+	if err != nil {
+		return nil, format, fmt.Errorf("%s: %v", path, err)
+	}
+	return samples, format, nil
+}
+
+// DecodeWavはWAV形式のデータを読み込む
+// dataチャンクのサイズが実際より大きい場合（書き込み途中のファイルなど）は終端までを読み込む
+// チャンクのサイズは信用せず、実際に読めたデータの分だけ領域を確保する
+func DecodeWav(r io.Reader) ([]float32, WavFormat, error) {
+	var format WavFormat
+
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, format, fmt.Errorf("WAVファイルではありません")
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, format, fmt.Errorf("WAVファイルではありません")
+	}
+
+	haveFormat := false
+	cues := make(map[uint32]*CuePoint)
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			break
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "fmt ":
+			body, err := readChunkBody(r, size)
+			if err != nil {
+				return nil, format, fmt.Errorf("fmtチャンクが不正です")
+			}
+			if err := parseWavFormat(body, &format); err != nil {
+				return nil, format, err
+			}
+			haveFormat = true
+		case "LIST", "bext", "cue ":
+			body, err := readChunkBody(r, size)
+			if err != nil {
+				return nil, format, truncatedChunkError(id, size, int64(len(body)))
+			}
+			parseInfoChunks(id, body, &format, cues)
+		case "data":
+			if !haveFormat {
+				return nil, format, fmt.Errorf("dataチャンクの前にfmtチャンクがありません")
+			}
+			pcm, err := io.ReadAll(io.LimitReader(r, size))
+			if err != nil {
+				return nil, format, fmt.Errorf("dataチャンクを読み込めませんでした: %v", err)
+			}
+			samples := decodeSamples(pcm, format)
+			format.Frames = len(samples) / format.Channels
+			format.Cues = sortedCues(cues)
+			return samples, format, nil
+		default:
+			// JUNKなど音声以外のチャンクは読み飛ばす
+			if n, err := io.CopyN(io.Discard, r, size); err != nil {
+				return nil, format, truncatedChunkError(id, size, n)
+			}
+		}
+
+		// チャンクは偶数バイト境界に揃える
+		if size%2 == 1 {
+			io.CopyN(io.Discard, r, 1)
+		}
+	}
+
+	if !haveFormat {
+		return nil, format, fmt.Errorf("fmtチャンクが見つかりません")
+	}
+	return nil, format, fmt.Errorf("dataチャンクが見つかりません")
+}
+
+// サイズの分だけ読めなかったチャンクのエラー（dataより前で途中で終わったファイルなど）
+func truncatedChunkError(id string, size int64, read int64) error {
+	return fmt.Errorf("%sチャンクが途中で終わっています（%d バイト中 %d バイト）", strings.TrimSpace(id), size, read)
+}
+
+// チャンクの本体を読み込む
+// サイズが壊れていてファイルの残りより大きい場合に備え、実際に読めた分だけ領域を確保する
+func readChunkBody(r io.Reader, size int64) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, size))
+	if err == nil && int64(len(body)) < size {
+		err = io.ErrUnexpectedEOF
+	}
+	return body, err
+}
+
+// fmtチャンクを解釈
+func parseWavFormat(body []byte, format *WavFormat) error {
+	if len(body) < 16 {
+		return fmt.Errorf("fmtチャンクが不正です")
+	}
+
+	tag := binary.LittleEndian.Uint16(body[0:2])
+	format.Channels = int(binary.LittleEndian.Uint16(body[2:4]))
+	format.SampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+	format.BitsPerSample = int(binary.LittleEndian.Uint16(body[14:16]))
+
+	// WAVE_FORMAT_EXTENSIBLEはサブフォーマットGUIDの先頭にフォーマットタグが入る
+	if tag == wavFormatExtensible {
+		if len(body) < 26 {
+			return fmt.Errorf("fmtチャンクが不正です")
+		}
+		tag = binary.LittleEndian.Uint16(body[24:26])
+	}
+
+	switch {
+	case tag == wavFormatPCM && (format.BitsPerSample == 8 || format.BitsPerSample == 16 ||
+		format.BitsPerSample == 24 || format.BitsPerSample == 32):
+	case tag == wavFormatFloat && (format.BitsPerSample == 32 || format.BitsPerSample == 64):
+		format.Float = true
+	default:
+		return fmt.Errorf("未対応のWAVフォーマットです（タグ: %d, %dbit）", tag, format.BitsPerSample)
+	}
+
+	if format.Channels < 1 || format.SampleRate < 1 {
+		return fmt.Errorf("fmtチャンクのチャンネル数またはサンプリングレートが不正です")
+	}
+	return nil
+}
+
+// dataチャンクのバイト列をサンプルに変換（端数のフレームは捨てる）
+func decodeSamples(pcm []byte, format WavFormat) []float32 {
+	bytesPerSample := format.BitsPerSample / 8
+	frameBytes := bytesPerSample * format.Channels
+	count := len(pcm) / frameBytes * format.Channels
+	samples := make([]float32, count)
+
+	for i := range samples {
+		b := pcm[i*bytesPerSample:]
+		switch {
+		case format.Float && bytesPerSample == 4:
+			samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(b))
+		case format.Float:
+			samples[i] = float32(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		case bytesPerSample == 1:
+			// 8bitは符号なし
+			samples[i] = float32(int(b[0])-128) / 128.0
+		case bytesPerSample == 2:
+			samples[i] = float32(int16(binary.LittleEndian.Uint16(b))) / 32768.0
+		case bytesPerSample == 3:
+			v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+			samples[i] = float32(v) / 8388608.0
+		default:
+			samples[i] = float32(float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648.0)
+		}
+	}
+	return samples
+}
@@ -0,0 +1,142 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// RIFFヘッダーとチャンクを並べたWAVのバイト列（RIFFのサイズは実際の長さ）
+func riffWav(chunks ...[]byte) []byte {
+	body := []byte("WAVE")
+	for _, chunk := range chunks {
+		body = append(body, chunk...)
+	}
+	data := []byte("RIFF")
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(body)))
+	return append(data, body...)
+}
+
+// サイズを指定したチャンク（サイズは本体の長さと違っていてもよい）
+func rawChunk(id string, size uint32, body []byte) []byte {
+	chunk := append([]byte(id), binary.LittleEndian.AppendUint32(nil, size)...)
+	return append(chunk, body...)
+}
+
+// 16bit PCMのfmtチャンクの本体
+func pcm16Format(channels int, sampleRate int) []byte {
+	body := binary.LittleEndian.AppendUint16(nil, wavFormatPCM)
+	body = binary.LittleEndian.AppendUint16(body, uint16(channels))
+	body = binary.LittleEndian.AppendUint32(body, uint32(sampleRate))
+	body = binary.LittleEndian.AppendUint32(body, uint32(sampleRate*channels*2))
+	body = binary.LittleEndian.AppendUint16(body, uint16(channels*2))
+	return binary.LittleEndian.AppendUint16(body, 16)
+}
+
+func pcm16Data(values ...int16) []byte {
+	var data []byte
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint16(data, uint16(v))
+	}
+	return data
+}
+
+func TestDecodeWavMalformed(t *testing.T) {
+	format := pcm16Format(1, 16000)
+	data := pcm16Data(16384, -16384, 0, 32767)
+
+	tests := []struct {
+		name    string
+		wav     []byte
+		frames  int    // 期待するフレーム数（エラーの場合は無視）
+		wantErr string // 期待するエラーの一部（空の場合はエラーなし）
+	}{
+		{"valid", riffWav(rawChunk("fmt ", 16, format), rawChunk("data", 8, data)), 4, ""},
+		{"not_riff", []byte("RIFX\x00\x00\x00\x00WAVE"), 0, "WAVファイルではありません"},
+		{"truncated_header", []byte("RIFF\x00"), 0, "WAVファイルではありません"},
+		{"no_fmt", riffWav(rawChunk("data", 8, data)), 0, "fmtチャンクがありません"},
+		{"no_data", riffWav(rawChunk("fmt ", 16, format)), 0, "dataチャンクが見つかりません"},
+		{"short_fmt", riffWav(rawChunk("fmt ", 8, format[:8]), rawChunk("data", 8, data)), 0, "fmtチャンクが不正です"},
+		{"unsupported_format", riffWav(rawChunk("fmt ", 16, append([]byte{0x55, 0}, format[2:]...)), rawChunk("data", 8, data)), 0, "未対応のWAVフォーマット"},
+		{"zero_channels", riffWav(rawChunk("fmt ", 16, pcm16Format(0, 16000)), rawChunk("data", 8, data)), 0, "チャンネル数"},
+		// 壊れたサイズのチャンクでファイルより大きな領域を確保しない
+		{"huge_fmt", riffWav(rawChunk("fmt ", 0xFFFFFFF0, format)), 0, "fmtチャンクが不正です"},
+		{"huge_list", riffWav(rawChunk("fmt ", 16, format), rawChunk("LIST", 0xFFFFFFF0, []byte("INFO"))), 0, "LISTチャンクが途中で終わっています"},
+		{"huge_junk", riffWav(rawChunk("fmt ", 16, format), rawChunk("JUNK", 0xFFFFFFF0, []byte("xx"))), 0, "JUNKチャンクが途中で終わっています"},
+		// dataより前のチャンクが途中で終わっている場合は、そのチャンクをエラーに示す
+		{"truncated_list", riffWav(rawChunk("fmt ", 16, format), rawChunk("LIST", 64, []byte("INFO")), rawChunk("data", 8, data)), 0, "LISTチャンクが途中で終わっています（64 バイト中 20 バイト）"},
+		{"truncated_bext", riffWav(rawChunk("fmt ", 16, format), rawChunk("bext", 602, make([]byte, 100))), 0, "bextチャンクが途中で終わっています"},
+		{"truncated_cue", riffWav(rawChunk("fmt ", 16, format), rawChunk("cue ", 28, []byte{1, 0, 0, 0})), 0, "cueチャンクが途中で終わっています"},
+		// 書き込み途中のファイルはdataの終端までを読み込む
+		{"huge_data", riffWav(rawChunk("fmt ", 16, format), rawChunk("data", 0xFFFFFFFF, data)), 4, ""},
+		// 端数のバイトは捨てる
+		{"partial_frame", riffWav(rawChunk("fmt ", 16, format), rawChunk("data", 9, append(data, 1))), 4, ""},
+		// 奇数サイズのチャンクの後のパディング
+		{"odd_junk", riffWav(rawChunk("fmt ", 16, format), rawChunk("JUNK", 3, []byte("abc\x00")), rawChunk("data", 8, data)), 4, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples, format, err := DecodeWav(bytes.NewReader(tt.wav))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("エラー = %v（期待値 %q を含む）", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeWav: %v", err)
+			}
+			if format.Frames != tt.frames || len(samples) != tt.frames {
+				t.Fatalf("フレーム数 = %d（%d サンプル、期待値 %d）", format.Frames, len(samples), tt.frames)
+			}
+			if samples[0] != 0.5 || samples[1] != -0.5 {
+				t.Errorf("サンプル = %v", samples)
+			}
+		})
+	}
+}
+
+func TestWavRoundTrip(t *testing.T) {
+	buffer := [][]float32{{0, 0.25, -0.25, 0.5, -0.5, 0.75}}
+	for _, f := range []SampleFormat{PCM16, PCM24, Float32} {
+		t.Run(f.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			if _, err := EncodeWav(&buf, buffer, 44100, 2, WavOptions{Format: f}); err != nil {
+				t.Fatal(err)
+			}
+			samples, format, err := DecodeWav(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format.Channels != 2 || format.SampleRate != 44100 || format.Frames != 3 || format.Float != (f == Float32) {
+				t.Fatalf("形式が違います: %+v", format)
+			}
+			// 整数PCMは量子化の誤差（1ステップ）まで許す
+			tolerance := 0.0
+			if f != Float32 {
+				tolerance = 1 / float64(int(1)<<(f.BitsPerSample()-1))
+			}
+			for i, want := range buffer[0] {
+				if math.Abs(float64(samples[i]-want)) > tolerance {
+					t.Errorf("サンプル %d = %v（期待値 %v）", i, samples[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadWavInfoTruncatedChunk(t *testing.T) {
+	// 形式だけを読み込む場合も途中で終わったチャンクを示す
+	path := filepath.Join(t.TempDir(), "truncated.wav")
+	wav := riffWav(rawChunk("fmt ", 16, pcm16Format(1, 16000)), rawChunk("LIST", 64, []byte("INFO")), rawChunk("data", 8, pcm16Data(1, 2, 3, 4)))
+	if err := os.WriteFile(path, wav, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadAudioInfo(path); err == nil || !strings.Contains(err.Error(), "LISTチャンクが途中で終わっています") {
+		t.Errorf("エラー = %v", err)
+	}
+}
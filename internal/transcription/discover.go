@@ -0,0 +1,301 @@
+package transcription
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"whisper_local_faster_whsiper_go/internal/app"
+)
+
+// whisper.cppの場所を指定する環境変数（フラグで指定した場合はフラグを優先する）
+const (
+	EnvWhisperCLI       = "WHISPER_CLI"        // whisper-cliのパス
+	EnvWhisperModel     = "WHISPER_MODEL"      // モデルファイルのパスまたは名前
+	EnvWhisperModelsDir = "WHISPER_MODELS_DIR" // モデルを探すディレクトリ
+	EnvWhisperCppDir    = "WHISPER_CPP_DIR"    // whisper.cppのソースディレクトリ
+)
+
+// モデルを指定しない場合に優先して使うモデル
+const defaultModel = "ggml-base.bin"
+
+// PATHとインストール先で探すwhisper.cppの実行ファイルの名前（新しい順）
+// 古いバージョンの名前のmainはPATH上の無関係なコマンドと区別できないため、whisper.cppのソースディレクトリだけで探す
+var whisperBinaryNames = []string{"whisper-cli", "whisper-cpp"}
+
+// 実行ファイルを探すインストール先
+var whisperPrefixes = []string{"/opt/homebrew", "/usr/local", "/usr", "~/.local"}
+
+// WhisperSettingsはwhisper.cppの実行ファイルとモデルの指定（空の場合は環境変数と探索で決める）
+type WhisperSettings struct {
+	Binary    string // whisper-cliのパス
+	Model     string // モデルファイルのパスまたは名前（例: base、ggml-small.bin）
+	ModelsDir string // モデルを探すディレクトリ
+}
+
+// WhisperInstallは見つかったwhisper.cppの実行ファイルとモデル
+type WhisperInstall struct {
+	Binary   string      // 使用する実行ファイル（見つからない場合は空）
+	Model    string      // 使用するモデルファイル（見つからない場合は空）
+	Models   []ModelFile // 見つかったモデル
+	Problems []string    // 足りないものの説明
+}
+
+// ModelFileは見つかったモデルファイル
+type ModelFile struct {
+	Name string // 名前（例: base）
+	Path string // パス
+	Size int64  // サイズ（バイト）
+}
+
+// 指定がない項目を環境変数で補う
+func (s WhisperSettings) withEnv() WhisperSettings {
+	if s.Binary == "" {
+		s.Binary = os.Getenv(EnvWhisperCLI)
+	}
+	if s.Model == "" {
+		s.Model = os.Getenv(EnvWhisperModel)
+	}
+	if s.ModelsDir == "" {
+		s.ModelsDir = os.Getenv(EnvWhisperModelsDir)
+	}
+	return s
+}
+
+// DiscoverWhisperはフラグ・環境変数・よくあるインストール先からwhisper.cppの実行ファイルとモデルを探す
+func DiscoverWhisper(settings WhisperSettings) WhisperInstall {
+	settings = settings.withEnv()
+	var install WhisperInstall
+
+	// 実行ファイル
+	if settings.Binary != "" {
+		path := expandHome(settings.Binary)
+		if resolved, err := exec.LookPath(path); err == nil {
+			install.Binary = resolved
+		} else if info, statErr := os.Stat(path); statErr == nil && !info.IsDir() {
+			install.Problems = append(install.Problems,
+				fmt.Sprintf("whisper-cliに実行権限がありません: %s（chmod +x で権限を付与してください）", path))
+		} else {
+			install.Problems = append(install.Problems,
+				fmt.Sprintf("指定されたwhisper-cliがありません: %s（-whisper-cli または %s を確認してください）", path, EnvWhisperCLI))
+		}
+	} else {
+		binary, searched := findWhisperBinary()
+		install.Binary = binary
+		if binary == "" {
+			install.Problems = append(install.Problems, fmt.Sprintf(
+				"whisper-cliが見つかりません。PATH上の %s と次の場所を探しました:\n    %s\n"+
+					"  whisper.cppをインストール（brew install whisper-cpp）またはビルドし、-whisper-cli または %s で実行ファイルを指定してください",
+				strings.Join(whisperBinaryNames, " / "), strings.Join(searched, "\n    "), EnvWhisperCLI))
+		}
+	}
+
+	// モデル
+	dirs := modelDirs(settings.ModelsDir, install.Binary)
+	install.Models = findModels(dirs)
+	install.Model = selectModel(settings.Model, install.Models)
+	if install.Model == "" {
+		switch {
+		case settings.Model != "" && len(install.Models) > 0:
+			install.Problems = append(install.Problems, fmt.Sprintf(
+				"指定されたモデルが見つかりません: %s（見つかったモデル: %s）",
+				settings.Model, strings.Join(modelNames(install.Models), ", ")))
+		case settings.Model != "":
+			install.Problems = append(install.Problems, fmt.Sprintf(
+				"指定されたモデルが見つかりません: %s。次のディレクトリを探しました:\n    %s",
+				settings.Model, strings.Join(dirs, "\n    ")))
+		default:
+			install.Problems = append(install.Problems, fmt.Sprintf(
+				"モデルファイル（ggml-*.bin）が見つかりません。次のディレクトリを探しました:\n    %s\n"+
+					"  whisper.cppの models/download-ggml-model.sh base でダウンロードし、"+
+					"-whisper-model・-whisper-models-dir または %s・%s で指定してください",
+				strings.Join(dirs, "\n    "), EnvWhisperModel, EnvWhisperModelsDir))
+		}
+	}
+	return install
+}
+
+// PATHとよくあるインストール先から実行ファイルを探す（見つからない場合は探した場所を返す）
+func findWhisperBinary() (string, []string) {
+	for _, name := range whisperBinaryNames {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+
+	var candidates []string
+	for _, dir := range whisperCppDirs() {
+		candidates = append(candidates,
+			filepath.Join(dir, "build", "bin", "whisper-cli"),
+			filepath.Join(dir, "build", "bin", "main"),
+			filepath.Join(dir, "main"))
+	}
+	for _, prefix := range whisperPrefixes {
+		for _, name := range whisperBinaryNames {
+			candidates = append(candidates, filepath.Join(expandHome(prefix), "bin", name))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return candidate, nil
+		}
+	}
+	return "", candidates
+}
+
+// whisper.cppのソースディレクトリの候補
+func whisperCppDirs() []string {
+	dirs := []string{
+		os.Getenv(EnvWhisperCppDir),
+		"whisper.cpp",
+		filepath.Join("..", "whisper.cpp"),
+		"~/whisper.cpp",
+		"~/github/whisper.cpp",
+		"~/src/whisper.cpp",
+		"~/dev/whisper.cpp",
+		"/opt/whisper.cpp",
+	}
+	return uniqueDirs(dirs)
+}
+
+// モデルを探すディレクトリ（指定したディレクトリ、実行ファイルのあるソースディレクトリ、よくある場所の順）
+func modelDirs(modelsDir, binary string) []string {
+	dirs := []string{modelsDir}
+	if binary != "" {
+		binDir := filepath.Dir(binary)
+		dirs = append(dirs,
+			filepath.Join(binDir, "..", "..", "models"), // <whisper.cpp>/build/bin
+			filepath.Join(binDir, "models"),
+			filepath.Join(binDir, "..", "share", "whisper-cpp"), // Homebrew
+			filepath.Join(binDir, "..", "share", "whisper-cpp", "models"))
+	}
+	dirs = append(dirs, "models", "~/.cache/whisper.cpp", "~/.local/share/whisper.cpp/models")
+	for _, dir := range whisperCppDirs() {
+		dirs = append(dirs, filepath.Join(dir, "models"))
+	}
+	return uniqueDirs(dirs)
+}
+
+// ディレクトリ内のモデルファイル（ggml-*.bin）を探す
+func findModels(dirs []string) []ModelFile {
+	var models []ModelFile
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		paths, _ := filepath.Glob(filepath.Join(dir, "ggml-*.bin"))
+		sort.Strings(paths)
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			// 同じモデルが複数の場所にある場合は先に見つかった方を使う
+			if seen[filepath.Base(path)] {
+				continue
+			}
+			seen[filepath.Base(path)] = true
+			absPath, _ := filepath.Abs(path)
+			models = append(models, ModelFile{Name: modelName(path), Path: absPath, Size: info.Size()})
+		}
+	}
+	return models
+}
+
+// 使用するモデルを選ぶ（指定がない場合はggml-base.bin、なければ最初に見つかったもの）
+func selectModel(model string, models []ModelFile) string {
+	if model != "" {
+		path := expandHome(model)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			absPath, _ := filepath.Abs(path)
+			return absPath
+		}
+		// 名前で指定された場合（base / ggml-base.bin など）
+		for _, m := range models {
+			if m.Name == model || filepath.Base(m.Path) == model {
+				return m.Path
+			}
+		}
+		return ""
+	}
+
+	for _, m := range models {
+		if filepath.Base(m.Path) == defaultModel {
+			return m.Path
+		}
+	}
+	if len(models) > 0 {
+		return models[0].Path
+	}
+	return ""
+}
+
+// CheckWhisperAvailabilityはWhisper.cppが使用可能か確認し、見つかったモデルを表示する
+// 使用できない場合は足りないものと指定方法をエラーで説明する
+func CheckWhisperAvailability(settings WhisperSettings) (WhisperInstall, error) {
+	install := DiscoverWhisper(settings)
+
+	if install.Binary != "" {
+		fmt.Printf("  whisper-cli: %s\n", install.Binary)
+	}
+	if len(install.Models) > 0 {
+		fmt.Printf("  見つかったモデル:\n")
+		for _, m := range install.Models {
+			mark := ""
+			if m.Path == install.Model {
+				mark = "（使用）"
+			}
+			fmt.Printf("    - %s: %s (%s)%s\n", m.Name, m.Path, app.FormatSize(m.Size), mark)
+		}
+	}
+	if install.Model != "" {
+		fmt.Printf("  使用するモデル: %s\n", install.Model)
+	}
+
+	if len(install.Problems) > 0 {
+		return install, fmt.Errorf("whisper.cppを使用できません:\n- %s", strings.Join(install.Problems, "\n- "))
+	}
+	return install, nil
+}
+
+// モデルの名前（ggml-base.bin → base）
+func modelName(path string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "ggml-"), ".bin")
+}
+
+func modelNames(models []ModelFile) []string {
+	names := make([]string, len(models))
+	for i, m := range models {
+		names[i] = m.Name
+	}
+	return names
+}
+
+// 先頭の ~ をホームディレクトリに置き換える
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// 空の項目を除き、~を展開して重複を取り除く
+func uniqueDirs(dirs []string) []string {
+	out := make([]string, 0, len(dirs))
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		dir = filepath.Clean(expandHome(dir))
+		if !seen[dir] {
+			seen[dir] = true
+			out = append(out, dir)
+		}
+	}
+	return out
+}
@@ -0,0 +1,223 @@
+package transcription
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 探索の対象を一時ディレクトリだけにする（HOME・PATH・カレントディレクトリ・インストール先）
+func isolateDiscovery(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, dir := range []string{"home", "path", "cwd"} {
+		os.MkdirAll(filepath.Join(root, dir), 0755)
+	}
+	t.Setenv("HOME", filepath.Join(root, "home"))
+	t.Setenv("PATH", filepath.Join(root, "path"))
+	for _, env := range []string{EnvWhisperCLI, EnvWhisperModel, EnvWhisperModelsDir, EnvWhisperCppDir} {
+		t.Setenv(env, "")
+	}
+
+	prefixes := whisperPrefixes
+	whisperPrefixes = []string{filepath.Join(root, "prefix")}
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(root, "cwd")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		whisperPrefixes = prefixes
+		os.Chdir(dir)
+	})
+	return root
+}
+
+// 空のファイルを作成する（実行ファイルの場合は実行権限を付ける）
+func touch(t *testing.T, path string, executable bool) string {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	mode := os.FileMode(0644)
+	if executable {
+		mode = 0755
+	}
+	if err := os.WriteFile(path, nil, mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiscoverWhisperBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, root string) WhisperSettings
+		want    string // 期待する実行ファイル（rootからの相対パス。空の場合は見つからない）
+		problem string // 期待する問題の説明の一部
+	}{
+		{
+			"flag_over_env",
+			func(t *testing.T, root string) WhisperSettings {
+				touch(t, filepath.Join(root, "env", "whisper-cli"), true)
+				t.Setenv(EnvWhisperCLI, filepath.Join(root, "env", "whisper-cli"))
+				return WhisperSettings{Binary: touch(t, filepath.Join(root, "flag", "whisper-cli"), true)}
+			},
+			"flag/whisper-cli", "",
+		},
+		{
+			"env_over_path",
+			func(t *testing.T, root string) WhisperSettings {
+				touch(t, filepath.Join(root, "path", "whisper-cli"), true)
+				t.Setenv(EnvWhisperCLI, touch(t, filepath.Join(root, "env", "whisper-cli"), true))
+				return WhisperSettings{}
+			},
+			"env/whisper-cli", "",
+		},
+		{
+			"path",
+			func(t *testing.T, root string) WhisperSettings {
+				touch(t, filepath.Join(root, "path", "whisper-cpp"), true)
+				return WhisperSettings{}
+			},
+			"path/whisper-cpp", "",
+		},
+		{
+			// PATH上のmainはwhisper.cppとは限らないため使わない
+			"main_on_path",
+			func(t *testing.T, root string) WhisperSettings {
+				touch(t, filepath.Join(root, "path", "main"), true)
+				return WhisperSettings{}
+			},
+			"", "whisper-cliが見つかりません",
+		},
+		{
+			// whisper.cppのソースディレクトリでは古いバージョンのmainも使う
+			"main_in_source_dir",
+			func(t *testing.T, root string) WhisperSettings {
+				t.Setenv(EnvWhisperCppDir, filepath.Join(root, "src", "whisper.cpp"))
+				touch(t, filepath.Join(root, "src", "whisper.cpp", "build", "bin", "main"), true)
+				return WhisperSettings{}
+			},
+			"src/whisper.cpp/build/bin/main", "",
+		},
+		{
+			"home_source_dir",
+			func(t *testing.T, root string) WhisperSettings {
+				touch(t, filepath.Join(root, "home", "whisper.cpp", "build", "bin", "whisper-cli"), true)
+				return WhisperSettings{}
+			},
+			"home/whisper.cpp/build/bin/whisper-cli", "",
+		},
+		{
+			"prefix",
+			func(t *testing.T, root string) WhisperSettings {
+				touch(t, filepath.Join(root, "prefix", "bin", "whisper-cli"), true)
+				return WhisperSettings{}
+			},
+			"prefix/bin/whisper-cli", "",
+		},
+		{
+			"not_executable",
+			func(t *testing.T, root string) WhisperSettings {
+				return WhisperSettings{Binary: touch(t, filepath.Join(root, "flag", "whisper-cli"), false)}
+			},
+			"", "whisper-cliに実行権限がありません",
+		},
+		{
+			"missing_flag",
+			func(t *testing.T, root string) WhisperSettings {
+				return WhisperSettings{Binary: filepath.Join(root, "flag", "whisper-cli")}
+			},
+			"", "指定されたwhisper-cliがありません",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := isolateDiscovery(t)
+			install := DiscoverWhisper(tt.setup(t, root))
+
+			want := ""
+			if tt.want != "" {
+				want = filepath.Join(root, tt.want)
+			}
+			if install.Binary != want {
+				t.Errorf("実行ファイル %q（期待値 %q）", install.Binary, want)
+			}
+			problems := strings.Join(install.Problems, "\n")
+			if tt.problem != "" && !strings.Contains(problems, tt.problem) {
+				t.Errorf("問題の説明に %q がありません:\n%s", tt.problem, problems)
+			}
+			if tt.problem == "" && strings.Contains(problems, "whisper-cli") {
+				t.Errorf("実行ファイルの問題が報告されました:\n%s", problems)
+			}
+		})
+	}
+}
+
+func TestDiscoverWhisperModel(t *testing.T) {
+	tests := []struct {
+		name    string
+		models  []string // modelsディレクトリに置くモデル
+		flag    string   // -whisper-model
+		env     string   // WHISPER_MODEL
+		want    string   // 期待するモデル（空の場合は見つからない）
+		problem string   // 期待する問題の説明の一部
+	}{
+		{"default_base", []string{"ggml-small.bin", "ggml-base.bin"}, "", "", "ggml-base.bin", ""},
+		{"first_found", []string{"ggml-small.bin", "ggml-tiny.bin"}, "", "", "ggml-small.bin", ""},
+		{"by_name", []string{"ggml-small.bin", "ggml-base.bin"}, "small", "", "ggml-small.bin", ""},
+		{"by_file_name", []string{"ggml-small.bin", "ggml-base.bin"}, "ggml-small.bin", "", "ggml-small.bin", ""},
+		{"flag_over_env", []string{"ggml-small.bin", "ggml-base.bin"}, "small", "base", "ggml-small.bin", ""},
+		{"env", []string{"ggml-small.bin", "ggml-base.bin"}, "", "small", "ggml-small.bin", ""},
+		{"unknown", []string{"ggml-small.bin", "ggml-base.bin"}, "large", "", "", "指定されたモデルが見つかりません: large（見つかったモデル: base, small）"},
+		{"unknown_no_models", nil, "large", "", "", "指定されたモデルが見つかりません: large。次のディレクトリを探しました"},
+		{"no_models", nil, "", "", "", "モデルファイル（ggml-*.bin）が見つかりません"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := isolateDiscovery(t)
+			modelsDir := filepath.Join(root, "models")
+			os.MkdirAll(modelsDir, 0755)
+			for _, model := range tt.models {
+				touch(t, filepath.Join(modelsDir, model), false)
+			}
+			t.Setenv(EnvWhisperModel, tt.env)
+
+			binary := touch(t, filepath.Join(root, "path", "whisper-cli"), true)
+			install := DiscoverWhisper(WhisperSettings{Model: tt.flag, ModelsDir: modelsDir})
+			if install.Binary != binary {
+				t.Errorf("実行ファイル %q（期待値 %q）", install.Binary, binary)
+			}
+
+			want := ""
+			if tt.want != "" {
+				want = filepath.Join(modelsDir, tt.want)
+			}
+			if install.Model != want {
+				t.Errorf("モデル %q（期待値 %q）", install.Model, want)
+			}
+			problems := strings.Join(install.Problems, "\n")
+			if tt.problem == "" && problems != "" || !strings.Contains(problems, tt.problem) {
+				t.Errorf("問題の説明 %q（期待値 %q）", problems, tt.problem)
+			}
+		})
+	}
+}
+
+func TestDiscoverWhisperModelNextToBinary(t *testing.T) {
+	// ソースディレクトリでビルドした実行ファイルの場合は<whisper.cpp>/modelsを探す
+	root := isolateDiscovery(t)
+	source := filepath.Join(root, "src", "whisper.cpp")
+	binary := touch(t, filepath.Join(source, "build", "bin", "whisper-cli"), true)
+	model := touch(t, filepath.Join(source, "models", "ggml-base.en.bin"), false)
+
+	install := DiscoverWhisper(WhisperSettings{Binary: binary})
+	if install.Model != model || len(install.Models) != 1 || install.Models[0].Name != "base.en" {
+		t.Errorf("モデル %q・見つかったモデル %+v（期待値 %q）", install.Model, install.Models, model)
+	}
+	if len(install.Problems) != 0 {
+		t.Errorf("問題が報告されました: %v", install.Problems)
+	}
+}
@@ -1,41 +1,61 @@
 package transcription
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"whisper_local_faster_whsiper_go/internal/analysis"
 	"whisper_local_faster_whsiper_go/internal/app"
+	"whisper_local_faster_whsiper_go/internal/audio"
 )
 
-// 音声ファイルを文字起こしして分析
-func ProcessAudio(application *app.App, audioPath string) {
+// 音声セグメントをtranscriberで文字起こしして分析
+func ProcessAudio(ctx context.Context, transcriber Transcriber, application *app.App, segment app.Segment) {
 	fmt.Print(app.SectionHeader("文字起こし処理開始"))
-	fmt.Printf("%s対象音声ファイル:%s %s%s%s\n", app.Bold, app.Reset, app.Cyan, audioPath, app.Reset)
+	fmt.Printf("%s対象音声ファイル:%s %s%s%s\n", app.Bold, app.Reset, app.Cyan, segment.Path, app.Reset)
+	fmt.Printf("%sセグメント:%s %s\n", app.Bold, app.Reset, segment.Label())
+
+	// 波形とスペクトログラム（文字起こしに失敗してもセッション全体の概要には含める）
+	images, err := application.RenderSegmentImages(segment)
+	if err != nil {
+		fmt.Printf("%s\n", app.WarningMessage("波形・スペクトログラムを作成できませんでした: "+err.Error()))
+	}
 
 	// 文字起こし
-	transcriptText, err := WhisperTranscribe(audioPath)
+	transcript, err := transcribeChannels(ctx, transcriber, application, segment.Path)
 	if err != nil {
 		fmt.Printf("%s\n", app.ErrorMessage("文字起こし失敗: "+err.Error()))
 		return
 	}
+	transcriptText := transcript.Text
+
+	// 保存ルールで削除できるよう文字起こし済みとして記録
+	application.MarkTranscribed(segment)
 
 	// 文字起こし結果を表示
 	fmt.Println(app.AnalysisHeader("文字起こし結果 (" + fmt.Sprintf("%d", len(transcriptText)) + "文字)"))
 	fmt.Println(app.TextBox(transcriptText, "文字起こし"))
+	if low := transcript.LowConfidence(); low > 0 {
+		fmt.Printf("%s\n", app.WarningMessage(fmt.Sprintf("信頼度が%.0f%%未満の区間が %d 件あります", lowConfidence*100, low)))
+	}
 
 	// 文字起こし結果を全体のリストに追加
+	application.AddBookmarkHint(segment, transcriptText)
 	application.Mutex.Lock()
 	application.AllTranscripts = append(application.AllTranscripts, transcriptText)
 	combinedText := strings.Join(application.AllTranscripts, " ")
+	hints := append([]string(nil), application.BookmarkHints...)
 	application.Mutex.Unlock()
 
 	// プログレスバー表示用のカウンター
-	totalTasks := 5 // タスク数を5に増やす（攻撃的言葉チェックを追加）
-	completedTasks := 0
+	totalTasks := 5                 // タスク数を5に増やす（攻撃的言葉チェックを追加）
+	var completedTasks atomic.Int32 // 分析の並行処理とプログレスバーの更新で共有
 
 	fmt.Println(app.SectionHeader("テキスト分析"))
 
@@ -56,14 +76,14 @@ func ProcessAudio(application *app.App, audioPath string) {
 			select {
 			case <-animDone:
 				// 完了時は100%に設定
-				_ = bar.Add(totalTasks - completedTasks) // 残りを一気に追加して完了させる
+				_ = bar.Add(totalTasks - int(bar.State().CurrentBytes)) // 残りを一気に追加して完了させる
 				return
 			default:
 				// 現在の大きさを取得
 				current := int(bar.State().CurrentBytes)
-				if completedTasks > current {
+				if completed := int(completedTasks.Load()); completed > current {
 					// 差分を追加
-					_ = bar.Add(completedTasks - current)
+					_ = bar.Add(completed - current)
 				}
 				time.Sleep(100 * time.Millisecond)
 			}
@@ -74,39 +94,39 @@ func ProcessAudio(application *app.App, audioPath string) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		result, err := analysis.GenerateSummary(combinedText)
+		result, err := analysis.GenerateSummary(combinedText, hints)
 		if err != nil {
 			fmt.Printf("\r%s\n", app.ErrorMessage("要約生成エラー: "+err.Error()))
 		} else {
 			summary = result
 		}
-		completedTasks++
+		completedTasks.Add(1)
 	}()
 
 	// キーワード抽出
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		result, err := analysis.ExtractKeywords(combinedText)
+		result, err := analysis.ExtractKeywords(combinedText, hints)
 		if err != nil {
 			fmt.Printf("\r%s\n", app.ErrorMessage("キーワード抽出エラー: "+err.Error()))
 		} else {
 			keywords = result
 		}
-		completedTasks++
+		completedTasks.Add(1)
 	}()
 
 	// 問題点抽出
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		result, err := analysis.IdentifyIssues(combinedText)
+		result, err := analysis.IdentifyIssues(combinedText, hints)
 		if err != nil {
 			fmt.Printf("\r%s\n", app.ErrorMessage("問題点抽出エラー: "+err.Error()))
 		} else {
 			issues = result
 		}
-		completedTasks++
+		completedTasks.Add(1)
 	}()
 
 	// 進行状況評価
@@ -119,7 +139,7 @@ func ProcessAudio(application *app.App, audioPath string) {
 		} else {
 			progressScore = result
 		}
-		completedTasks++
+		completedTasks.Add(1)
 	}()
 
 	// 攻撃的言葉チェック
@@ -132,7 +152,7 @@ func ProcessAudio(application *app.App, audioPath string) {
 		} else {
 			aggressiveCheck = result
 		}
-		completedTasks++
+		completedTasks.Add(1)
 	}()
 
 	// すべての分析が完了するまで待機
@@ -177,26 +197,134 @@ func ProcessAudio(application *app.App, audioPath string) {
 	}
 
 	// マークダウンに保存
-	saveMarkdown(application, transcriptText, combinedText, summary, keywords, issues, progressScore, aggressiveCheck)
+	saveMarkdown(application, segment, images, transcript, combinedText, summary, keywords, issues, progressScore, aggressiveCheck)
 
 	fmt.Printf("%s\n", app.SuccessMessage("文字起こしと分析が完了しました"))
 	fmt.Printf("%s結果は以下に保存されました:%s %s\n", app.Bold, app.Reset, application.MdFile)
 	fmt.Println(app.SectionHeader("処理完了"))
 }
 
+// チャンネルごとに文字起こしし、話者名を付けて結合
+// モノラルの場合はそのまま文字起こしする（FLACや16kHz以外のファイルは一時的にWAVに変換する）
+func transcribeChannels(ctx context.Context, transcriber Transcriber, application *app.App, audioPath string) (Transcript, error) {
+	tmpDir, err := os.MkdirTemp("", "whisper_input_")
+	if err != nil {
+		return Transcript{}, fmt.Errorf("一時ディレクトリを作成できませんでした: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if audio.IsFlac(audioPath) {
+		wavPath := filepath.Join(tmpDir, strings.TrimSuffix(filepath.Base(audioPath), filepath.Ext(audioPath))+".wav")
+		if err := audio.ConvertToWav(audioPath, wavPath); err != nil {
+			return Transcript{}, err
+		}
+		audioPath = wavPath
+	}
+
+	channelFiles, err := audio.ChannelFiles(audioPath)
+	if err != nil {
+		return Transcript{}, err
+	}
+	for i, channelFile := range channelFiles {
+		if channelFiles[i], err = whisperInput(channelFile, tmpDir); err != nil {
+			return Transcript{}, err
+		}
+	}
+
+	if len(channelFiles) == 1 {
+		result, err := transcriber.Transcribe(ctx, Request{Path: channelFiles[0]})
+		if err != nil {
+			return Transcript{}, err
+		}
+		return Transcript{Text: result.Text, Channels: []ChannelTranscript{{Result: result}}}, nil
+	}
+
+	var transcript Transcript
+	parts := make([]string, 0, len(channelFiles))
+	for ch, channelFile := range channelFiles {
+		result, err := transcriber.Transcribe(ctx, Request{Path: channelFile})
+		if err != nil {
+			fmt.Printf("%s\n", app.ErrorMessage(fmt.Sprintf("%sの文字起こし失敗: %v", application.ChannelLabel(ch), err)))
+			continue
+		}
+		if result.Text == "" {
+			continue
+		}
+		speaker := application.ChannelLabel(ch)
+		transcript.Channels = append(transcript.Channels, ChannelTranscript{Speaker: speaker, Result: result})
+		parts = append(parts, fmt.Sprintf("[%s] %s", speaker, result.Text))
+	}
+
+	if len(parts) == 0 {
+		return Transcript{}, fmt.Errorf("すべてのチャンネルで文字起こしに失敗しました")
+	}
+	transcript.Text = strings.Join(parts, "\n")
+	return transcript, nil
+}
+
+// whisperに渡すファイルのパスを返す
+// 16kHz以外のファイルは16kHzに変換してtmpDirに保存する
+func whisperInput(path string, tmpDir string) (string, error) {
+	format, err := audio.ReadAudioInfo(path)
+	if err != nil {
+		return "", err
+	}
+	if format.SampleRate == audio.WhisperSampleRate {
+		return path, nil
+	}
+
+	samples, format, err := audio.ReadWav(path)
+	if err != nil {
+		return "", err
+	}
+	perChannel := audio.Deinterleave(samples, format.Channels)
+	for c := range perChannel {
+		perChannel[c] = audio.Resample(perChannel[c], format.SampleRate, audio.WhisperSampleRate)
+	}
+
+	wavPath := filepath.Join(tmpDir, filepath.Base(path))
+	_, err = audio.WriteWavFile(wavPath, [][]float32{audio.Interleave(perChannel)}, audio.WhisperSampleRate, format.Channels, audio.WavOptions{Metadata: format.Metadata})
+	return wavPath, err
+}
+
 // マークダウンに保存
-func saveMarkdown(application *app.App, currentTranscript, combinedText, summary string, keywords []string, issues, progressScore, aggressiveCheck string) {
+func saveMarkdown(application *app.App, segment app.Segment, images app.Images, transcript Transcript, combinedText, summary string, keywords []string, issues, progressScore, aggressiveCheck string) {
 	// ファイルが存在しない場合は初期化
 	if _, err := os.Stat(application.MdFile); os.IsNotExist(err) {
 		application.InitializeMarkdownFile()
 	}
 
-	// 追記用の内容
-	timestampDisplay := time.Now().Format("2006-01-02 15:04:05")
-
-	// 追記内容の作成
+	// 追記内容の作成（見出しは録音開始からの経過時刻）
 	var content strings.Builder
-	content.WriteString(fmt.Sprintf("\n## %s\n\n%s\n\n", timestampDisplay, currentTranscript))
+	content.WriteString(fmt.Sprintf("\n## %s\n\n", segment.Label()))
+	if segment.Dropped > 0 {
+		content.WriteString(fmt.Sprintf("> ⚠ このセグメントでは %d フレーム（%.2f 秒）の音声が欠落しています\n\n",
+			segment.Dropped, float64(segment.Dropped)/float64(segment.SampleRate)))
+	}
+	if segment.Clipped > 0 {
+		content.WriteString(fmt.Sprintf("> ⚠ このセグメントでは %d サンプルがクリップしています（入力レベルが高すぎます）\n\n", segment.Clipped))
+	}
+	content.WriteString(fmt.Sprintf("%s\n\n", transcript.Text))
+	if len(segment.Bookmarks) > 0 {
+		content.WriteString(fmt.Sprintf("### ブックマーク\n\n%s\n", segment.BookmarkList()))
+	}
+	if timeline := transcript.Timeline(segment.StartOffset()); timeline != "" {
+		content.WriteString("### 発話のタイムスタンプ\n\n")
+		var notes []string
+		if languages := transcript.Languages(); len(languages) > 0 {
+			notes = append(notes, "言語: "+strings.Join(languages, ", "))
+		}
+		if confidence, ok := transcript.Confidence(); ok {
+			notes = append(notes, fmt.Sprintf("平均信頼度: %.0f%%", confidence*100))
+		}
+		if len(notes) > 0 {
+			content.WriteString(strings.Join(notes, " / ") + "\n\n")
+		}
+		content.WriteString(timeline + "\n")
+	}
+	if images.Waveform != "" {
+		content.WriteString(fmt.Sprintf("### 波形とスペクトログラム\n\n%s\n", images.Markdown()))
+	}
 	content.WriteString(fmt.Sprintf("### 全体テキスト\n\n%s\n\n", combinedText))
 
 	if summary != "" {
@@ -0,0 +1,229 @@
+package transcription
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"whisper_local_faster_whsiper_go/internal/analysis"
+	"whisper_local_faster_whsiper_go/internal/app"
+	"whisper_local_faster_whsiper_go/internal/audio"
+)
+
+// すべての問い合わせに同じ応答を返すOllamaのスタブ
+func newOllamaStub(t *testing.T, response string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/generate" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"response": response})
+	}))
+	original := analysis.OllamaAPIURL
+	analysis.OllamaAPIURL = server.URL
+	t.Cleanup(func() {
+		analysis.OllamaAPIURL = original
+		server.Close()
+	})
+}
+
+// 一時ディレクトリのdata以下に保存するアプリケーション
+func newProcessTestApp(t *testing.T) *app.App {
+	t.Helper()
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(dir) })
+	return app.NewApp()
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestProcessPendingFilesWithFake(t *testing.T) {
+	newOllamaStub(t, "分析結果")
+	a := newProcessTestApp(t)
+
+	// メタデータ付きの2つのセグメントを録音ディレクトリに置き、ディレクトリごと処理待ちにする
+	names := []string{"recording_20250101_120000_0001.wav", "recording_20250101_120030_0002.wav"}
+	for i, name := range names {
+		buffer := [][]float32{make([]float32, 16000)}
+		metadata := &audio.WavMetadata{SessionID: "20250101_120000", Sequence: i + 1}
+		if _, err := audio.WriteWavFile(filepath.Join(a.RecordingDir, name), buffer, 16000, 1, audio.WavOptions{Metadata: metadata}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := a.QueueFiles([]string{a.RecordingDir}); err != nil || n != len(names) {
+		t.Fatalf("処理待ちに追加したファイル %d 件・エラー %v", n, err)
+	}
+
+	var processed []string
+	a.SetProcessAudioFunc(func(a *app.App, segment app.Segment) {
+		processed = append(processed, filepath.Base(segment.Path))
+		ProcessAudio(context.Background(), NewFakeTranscriber(""), a, segment)
+	})
+	a.ProcessPendingFiles()
+
+	if len(processed) != len(names) || processed[0] != names[0] || processed[1] != names[1] {
+		t.Errorf("処理した順序 %v（期待値 %v）", processed, names)
+	}
+	if len(a.PendingFiles) != 0 {
+		t.Errorf("処理待ちが残っています: %+v", a.PendingFiles)
+	}
+	if len(a.AllTranscripts) != len(names) {
+		t.Errorf("文字起こし結果 %d 件（期待値 %d）", len(a.AllTranscripts), len(names))
+	}
+
+	markdown := readFile(t, a.MdFile)
+	for _, want := range []string{
+		names[0] + " の文字起こし（1.0 秒）",
+		names[1] + " の文字起こし（1.0 秒）",
+		"### 発話のタイムスタンプ",
+		"### 全体要約\n\n分析結果",
+		"### 全体キーワード\n\n分析結果",
+		"### 攻撃的言葉チェック\n\n分析結果",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("マークダウンに %q がありません:\n%s", want, markdown)
+		}
+	}
+	if strings.Count(markdown, "\n## ") != len(names) {
+		t.Errorf("セグメントの見出しが %d 件ではありません:\n%s", len(names), markdown)
+	}
+
+	manifest := readFile(t, filepath.Join(a.RecordingDir, "transcribed.txt"))
+	for _, name := range names {
+		if !strings.Contains(manifest, strings.TrimSuffix(name, ".wav")+"\n") {
+			t.Errorf("%s が文字起こし済みとして記録されていません: %q", name, manifest)
+		}
+	}
+}
+
+func TestProcessAudioTranscriptionError(t *testing.T) {
+	newOllamaStub(t, "分析結果")
+	a := newProcessTestApp(t)
+
+	// 読めない音声は文字起こし済みにせず、マークダウンにも書き込まない
+	path := filepath.Join(a.RecordingDir, "recording_20250101_120000_0001.wav")
+	if err := os.WriteFile(path, []byte("not a wav"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ProcessAudio(context.Background(), NewFakeTranscriber(""), a, app.Segment{Path: path})
+
+	if _, err := os.Stat(a.MdFile); !os.IsNotExist(err) {
+		t.Errorf("マークダウンが作成されました: %s", readFile(t, a.MdFile))
+	}
+	if _, err := os.Stat(filepath.Join(a.RecordingDir, "transcribed.txt")); !os.IsNotExist(err) {
+		t.Error("文字起こしに失敗したセグメントが文字起こし済みとして記録されました")
+	}
+}
+
+func TestTranscribeChannels(t *testing.T) {
+	a := newProcessTestApp(t)
+	a.ChannelNames = []string{"司会"}
+	fake := NewFakeTranscriber("")
+
+	// 2チャンネルのWAVはチャンネル別に文字起こしし、話者名を付ける
+	stereo := filepath.Join(a.RecordingDir, "stereo.wav")
+	if _, err := audio.WriteWavFile(stereo, [][]float32{make([]float32, 32000)}, 16000, 2, audio.WavOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	transcript, err := transcribeChannels(context.Background(), fake, a, stereo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[司会] stereo_ch1.wav の文字起こし（1.0 秒）\n[チャンネル2] stereo_ch2.wav の文字起こし（1.0 秒）"
+	if transcript.Text != want || len(transcript.Channels) != 2 {
+		t.Errorf("文字起こし結果 %q（期待値 %q）", transcript.Text, want)
+	}
+
+	// FLACは一時的にWAVに変換して文字起こしする
+	flac := filepath.Join(a.RecordingDir, "mono.flac")
+	if _, err := audio.WriteFlacFile(flac, [][]float32{make([]float32, 8000)}, 16000, 1, audio.PCM16, audio.FlacOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	transcript, err = transcribeChannels(context.Background(), fake, a, flac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "mono.wav の文字起こし（0.5 秒）"; transcript.Text != want {
+		t.Errorf("文字起こし結果 %q（期待値 %q）", transcript.Text, want)
+	}
+	if transcript.Channels[0].Speaker != "" {
+		t.Errorf("モノラルに話者名が付きました: %q", transcript.Channels[0].Speaker)
+	}
+}
+
+// 渡されたファイルの形式を記録するバックエンド
+type formatRecorder struct {
+	FakeTranscriber
+	formats []audio.WavFormat
+}
+
+func (t *formatRecorder) Transcribe(ctx context.Context, request Request) (Result, error) {
+	format, err := audio.ReadAudioInfo(request.Path)
+	if err != nil {
+		return Result{}, err
+	}
+	t.formats = append(t.formats, format)
+	return t.FakeTranscriber.Transcribe(ctx, request)
+}
+
+func TestTranscribeChannelsResamples(t *testing.T) {
+	a := newProcessTestApp(t)
+
+	// 他の機器で録音した44.1kHz・48kHzのファイルも16kHzに変換してからwhisperに渡す
+	tests := []struct {
+		name       string
+		sampleRate int
+		channels   int
+	}{
+		{"mono_44100.wav", 44100, 1},
+		{"stereo_48000.wav", 48000, 2},
+		{"mono_44100.flac", 44100, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(a.RecordingDir, tt.name)
+			buffer := [][]float32{make([]float32, tt.sampleRate*tt.channels)}
+			var err error
+			if audio.IsFlac(path) {
+				_, err = audio.WriteFlacFile(path, buffer, tt.sampleRate, tt.channels, audio.PCM16, audio.FlacOptions{})
+			} else {
+				_, err = audio.WriteWavFile(path, buffer, tt.sampleRate, tt.channels, audio.WavOptions{})
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := &formatRecorder{}
+			if _, err := transcribeChannels(context.Background(), recorder, a, path); err != nil {
+				t.Fatal(err)
+			}
+			if len(recorder.formats) != tt.channels {
+				t.Fatalf("文字起こししたファイル %d 件（期待値 %d）", len(recorder.formats), tt.channels)
+			}
+			for _, format := range recorder.formats {
+				if format.SampleRate != audio.WhisperSampleRate || format.Channels != 1 || format.Frames != audio.WhisperSampleRate {
+					t.Errorf("whisperに渡した形式 %s（期待値 16000 Hz・1ch・1秒）", format)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package transcription
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// whisper.cppサーバーの文字起こしのエンドポイント
+const serverInferencePath = "/inference"
+
+// ServerTranscriberはwhisper.cppのHTTPサーバー（whisper-server）に音声を送信して文字起こしする
+// モデルを読み込んだままにできるため、セグメントごとにwhisper-cliを起動するより速い
+type ServerTranscriber struct {
+	URL      string // サーバーのURL（例: http://127.0.0.1:8080）
+	Language string // 言語（空の場合は自動検出）
+	Client   *http.Client
+}
+
+// 新しいサーバーのバックエンドを作成
+func NewServerTranscriber(url, language string) *ServerTranscriber {
+	url = strings.TrimSuffix(strings.TrimRight(url, "/"), serverInferencePath)
+	return &ServerTranscriber{URL: url, Language: language, Client: &http.Client{}}
+}
+
+func (t *ServerTranscriber) Name() string {
+	return BackendServer
+}
+
+// サーバーに接続できるか確認する
+func (t *ServerTranscriber) Check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.URL, nil)
+	if err != nil {
+		return fmt.Errorf("whisper.cppサーバーのURLが不正です: %v", err)
+	}
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("whisper.cppサーバーに接続できません（%s）: %v", t.URL, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (t *ServerTranscriber) Transcribe(ctx context.Context, request Request) (Result, error) {
+	audioPath, cleanup, err := request.file()
+	if err != nil {
+		return Result{}, err
+	}
+	defer cleanup()
+
+	fmt.Printf("  文字起こし中: %s...（%s）\n", request.name(), t.URL)
+	started := time.Now()
+
+	// multipart/form-dataで音声ファイルと設定を送信
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writeFormFile(writer, "file", audioPath); err != nil {
+		return Result{}, err
+	}
+	language := request.language(t.Language)
+	writer.WriteField("language", language)
+	writer.WriteField("response_format", "verbose_json")
+	writer.WriteField("temperature", "0.0")
+	if err := writer.Close(); err != nil {
+		return Result{}, fmt.Errorf("リクエストの作成に失敗: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL+serverInferencePath, &body)
+	if err != nil {
+		return Result{}, fmt.Errorf("リクエストの作成に失敗: %v", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return Result{}, fmt.Errorf("whisper.cppサーバーへの送信に失敗: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Result{}, fmt.Errorf("whisper.cppサーバーの応答を読み込めませんでした: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var response struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &response) != nil || response.Error == "" {
+			response.Error = strings.TrimSpace(string(data))
+		}
+		return Result{}, fmt.Errorf("whisper.cppサーバーのエラー（HTTP %d）: %s", resp.StatusCode, response.Error)
+	}
+
+	result, err := parseServerJSON(data)
+	if err != nil {
+		return Result{}, err
+	}
+	if result.Language == "" {
+		result.Language = knownLanguage(language)
+	}
+	result.Backend = t.Name()
+	result.Elapsed = time.Since(started)
+	fmt.Printf("  文字起こし完了: %s (%d文字)\n", request.name(), len(result.Text))
+	return result, nil
+}
+
+// whisper.cppサーバーの response_format=verbose_json の形式
+type serverJSON struct {
+	Text             string `json:"text"`
+	Language         string `json:"language"`
+	DetectedLanguage string `json:"detected_language"`
+	Error            string `json:"error"`
+	Segments         []struct {
+		Start float64 `json:"start"` // 秒
+		End   float64 `json:"end"`
+		Text  string  `json:"text"`
+		Words []struct {
+			Word        string  `json:"word"`
+			Start       float64 `json:"start"`
+			End         float64 `json:"end"`
+			Probability float64 `json:"probability"`
+		} `json:"words"`
+	} `json:"segments"`
+}
+
+// サーバーの応答を解釈する（区間・トークンの確率・検出した言語）
+// 区間のない応答（response_format=json）はテキストだけを返す
+func parseServerJSON(data []byte) (Result, error) {
+	var response serverJSON
+	if err := json.Unmarshal(data, &response); err != nil {
+		return Result{}, fmt.Errorf("whisper.cppサーバーの応答が不正です: %s", strings.TrimSpace(string(data)))
+	}
+	if response.Error != "" {
+		return Result{}, fmt.Errorf("whisper.cppサーバーのエラー: %s", response.Error)
+	}
+
+	result := Result{Text: strings.TrimSpace(response.Text), Language: response.DetectedLanguage}
+	if result.Language == "" {
+		result.Language = response.Language
+	}
+
+	texts := make([]string, 0, len(response.Segments))
+	for _, item := range response.Segments {
+		segment := TextSegment{
+			Start: seconds(item.Start),
+			End:   seconds(item.End),
+			Text:  strings.TrimSpace(item.Text),
+		}
+		for _, word := range item.Words {
+			if isSpecialToken(strings.TrimSpace(word.Word)) {
+				continue
+			}
+			segment.Tokens = append(segment.Tokens, Token{
+				Text:        word.Word,
+				Probability: word.Probability,
+				Start:       seconds(word.Start),
+				End:         seconds(word.End),
+			})
+		}
+		if segment.Text == "" {
+			continue
+		}
+		result.Segments = append(result.Segments, segment)
+		texts = append(texts, segment.Text)
+	}
+	if len(texts) > 0 {
+		result.Text = strings.Join(texts, "\n")
+	}
+	return result, nil
+}
+
+// 秒を時間に変換
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// ファイルをフォームの項目として追加
+func writeFormFile(writer *multipart.Writer, field, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("オーディオファイルを開けませんでした: %v", err)
+	}
+	defer file.Close()
+
+	part, err := writer.CreateFormFile(field, filepath.Base(path))
+	if err != nil {
+		return fmt.Errorf("リクエストの作成に失敗: %v", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return fmt.Errorf("オーディオファイルを読み込めませんでした: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package transcription
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"whisper_local_faster_whsiper_go/internal/audio"
+)
+
+// whisper.cppサーバーの verbose_json の応答
+const verboseJSON = `{
+  "task": "transcribe",
+  "language": "japanese",
+  "duration": 4.0,
+  "text": " こんにちは 今日の議題です",
+  "segments": [
+    {"id": 0, "text": " こんにちは", "start": 0.0, "end": 1.5,
+     "words": [{"word": "[_BEG_]", "start": 0.0, "end": 0.0, "probability": 1.0},
+               {"word": " こん", "start": 0.0, "end": 0.8, "probability": 0.9},
+               {"word": "にちは", "start": 0.8, "end": 1.5, "probability": 0.7}]},
+    {"id": 1, "text": " 今日の議題です", "start": 1.5, "end": 4.0,
+     "words": [{"word": " 今日の議題です", "start": 1.5, "end": 4.0, "probability": 0.2}]}
+  ],
+  "detected_language": "japanese",
+  "detected_language_probability": 0.98
+}`
+
+func writeTestWav(t *testing.T, seconds float64) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "segment.wav")
+	samples := make([]float32, int(seconds*16000))
+	if err := audio.SaveAsWav(path, [][]float32{samples}, 16000); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestServerTranscriberVerboseJSON(t *testing.T) {
+	var form map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != serverInferencePath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("multipartを解釈できません: %v", err)
+		}
+		if _, _, err := r.FormFile("file"); err != nil {
+			t.Errorf("音声ファイルがありません: %v", err)
+		}
+		form = map[string]string{
+			"language":        r.FormValue("language"),
+			"response_format": r.FormValue("response_format"),
+		}
+		w.Write([]byte(verboseJSON))
+	}))
+	defer server.Close()
+
+	transcriber := NewServerTranscriber(server.URL+"/inference", "")
+	result, err := transcriber.Transcribe(context.Background(), Request{Path: writeTestWav(t, 4)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if form["response_format"] != "verbose_json" || form["language"] != LanguageAuto {
+		t.Errorf("送信した設定 = %v", form)
+	}
+	if result.Language != "japanese" || result.Text != "こんにちは\n今日の議題です" {
+		t.Errorf("結果 = %q（言語: %q）", result.Text, result.Language)
+	}
+	if len(result.Segments) != 2 {
+		t.Fatalf("区間の数 = %d（期待値 2）", len(result.Segments))
+	}
+	first := result.Segments[0]
+	if first.Start != 0 || first.End != 1500*time.Millisecond || len(first.Tokens) != 2 {
+		t.Errorf("最初の区間 = %+v", first)
+	}
+	if confidence, ok := first.Confidence(); !ok || confidence < 0.79 || confidence > 0.81 {
+		t.Errorf("信頼度 = %v", confidence)
+	}
+	if result.Segments[1].Start != 1500*time.Millisecond || result.Segments[1].End != 4*time.Second {
+		t.Errorf("2番目の区間 = %+v", result.Segments[1])
+	}
+}
+
+func TestServerTranscriberError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "failed to read audio"}`))
+	}))
+	defer server.Close()
+
+	transcriber := NewServerTranscriber(server.URL, "ja")
+	if _, err := transcriber.Transcribe(context.Background(), Request{Path: writeTestWav(t, 1)}); err == nil {
+		t.Error("サーバーのエラーが返りません")
+	}
+}
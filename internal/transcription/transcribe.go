@@ -1,46 +1,70 @@
 package transcription
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
-func TranscribeWithShellScript(transcribeScript string, audioPath string) (string, error) {
-	// 絶対パスに変換
-	audioAbsPath, err := filepath.Abs(audioPath)
-	if err != nil {
-		return "", fmt.Errorf("絶対パスの取得に失敗: %v", err)
-	}
+// ScriptTranscriberは文字起こしスクリプトを実行し、出力された「音声ファイル名.txt」を読み込む
+// スクリプトは音声ファイルのパスを1つだけ引数に受け取る
+type ScriptTranscriber struct {
+	Script   string   // 文字起こしスクリプトのパス
+	Language string   // 言語（WHISPER_LANGUAGEとしてスクリプトに渡す）
+	Env      []string // スクリプトに追加で渡す環境変数（KEY=VALUE）
+}
 
-	// オーディオファイルの存在チェック
-	if _, err := os.Stat(audioAbsPath); os.IsNotExist(err) {
-		return "", fmt.Errorf("オーディオファイルが見つかりません: %s", audioAbsPath)
-	}
+// 新しいスクリプトのバックエンドを作成
+func NewScriptTranscriber(script, language string) *ScriptTranscriber {
+	return &ScriptTranscriber{Script: script, Language: language}
+}
+
+func (t *ScriptTranscriber) Name() string {
+	return BackendScript
+}
 
-	// シェルスクリプトの実行権限確認と付与
-	info, err := os.Stat(transcribeScript)
+// スクリプトがあるか確認し、実行権限がなければ付与する
+func (t *ScriptTranscriber) Check(ctx context.Context) error {
+	info, err := os.Stat(t.Script)
 	if err != nil {
-		return "", fmt.Errorf("文字起こしスクリプトがありません: %v", err)
+		return fmt.Errorf("文字起こしスクリプトがありません: %v", err)
 	}
 
 	// 実行権限を確認し、必要なら追加
 	if info.Mode()&0111 == 0 {
-		err = os.Chmod(transcribeScript, 0755)
+		err = os.Chmod(t.Script, 0755)
 		if err != nil {
-			return "", fmt.Errorf("文字起こしスクリプトに実行権限を付与できませんでした: %v", err)
+			return fmt.Errorf("文字起こしスクリプトに実行権限を付与できませんでした: %v", err)
 		}
 	}
+	return nil
+}
+
+func (t *ScriptTranscriber) Transcribe(ctx context.Context, request Request) (Result, error) {
+	audioAbsPath, cleanup, err := request.file()
+	if err != nil {
+		return Result{}, err
+	}
+	defer cleanup()
+
+	if err := t.Check(ctx); err != nil {
+		return Result{}, err
+	}
+	started := time.Now()
 
 	// シェルスクリプトを実行
-	cmd := exec.Command(transcribeScript, audioAbsPath)
-	fmt.Printf("  文字起こしコマンド: %s %s\n", transcribeScript, audioAbsPath)
+	cmd := exec.CommandContext(ctx, t.Script, audioAbsPath)
+	cmd.Env = append(os.Environ(), t.Env...)
+	cmd.Env = append(cmd.Env, "WHISPER_LANGUAGE="+request.language(t.Language))
+	fmt.Printf("  文字起こしコマンド: %s %s\n", t.Script, audioAbsPath)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return "", fmt.Errorf("文字起こし実行エラー: %v\n出力: %s", err, string(output))
+		return Result{}, fmt.Errorf("文字起こし実行エラー: %v\n出力: %s", err, string(output))
 	}
 
 	fmt.Printf("  出力: %s\n", string(output))
@@ -51,9 +75,14 @@ func TranscribeWithShellScript(transcribeScript string, audioPath string) (strin
 		fmt.Printf("  文字起こしファイル読み込み: %s\n", txtPath)
 		content, err := os.ReadFile(txtPath)
 		if err != nil {
-			return "", fmt.Errorf("文字起こしファイル読み込みエラー: %v", err)
+			return Result{}, fmt.Errorf("文字起こしファイル読み込みエラー: %v", err)
 		}
-		return strings.TrimSpace(string(content)), nil
+		return Result{
+			Text:     strings.TrimSpace(string(content)),
+			Language: knownLanguage(request.language(t.Language)),
+			Backend:  t.Name(),
+			Elapsed:  time.Since(started),
+		}, nil
 	} else {
 		fmt.Printf("  文字起こしファイルが見つかりません: %s\n", txtPath)
 
@@ -71,6 +100,6 @@ func TranscribeWithShellScript(transcribeScript string, audioPath string) (strin
 				fmt.Printf("  ディレクトリ内のテキストファイル: %v\n", txtFiles)
 			}
 		}
-		return "", fmt.Errorf("文字起こし結果が見つかりません")
+		return Result{}, fmt.Errorf("文字起こし結果が見つかりません")
 	}
 }
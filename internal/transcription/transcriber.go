@@ -0,0 +1,210 @@
+package transcription
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"whisper_local_faster_whsiper_go/internal/audio"
+)
+
+// 文字起こしのバックエンド
+const (
+	BackendWhisperCLI = "whisper-cli" // whisper.cppのwhisper-cliを実行する
+	BackendScript     = "script"      // 文字起こしスクリプト（transcribe.sh）を実行する
+	BackendServer     = "server"      // whisper.cppのHTTPサーバーに送信する
+	BackendFake       = "fake"        // 音声を解析せず決まった結果を返す（動作確認用）
+)
+
+// 言語を自動検出する指定（whisper.cppの -l auto）
+const LanguageAuto = "auto"
+
+// Transcriberは音声を文字起こしするバックエンド
+type Transcriber interface {
+	// バックエンドの名前（表示用）
+	Name() string
+	// 使用できる状態か確認する
+	Check(ctx context.Context) error
+	// 音声を文字起こしする
+	Transcribe(ctx context.Context, request Request) (Result, error)
+}
+
+// Requestは文字起こしする音声（ファイルまたはサンプル）
+type Request struct {
+	Path       string    // 音声ファイル（WAV）
+	Samples    []float32 // Pathの代わりに渡すモノラルのサンプル
+	SampleRate int       // Samplesのサンプリングレート
+	Language   string    // 言語（空の場合はバックエンドの設定、それもない場合は自動検出）
+}
+
+// Resultは文字起こしの結果
+type Result struct {
+	Text     string        // 全体のテキスト
+	Language string        // 言語（分かる場合）
+	Segments []TextSegment // 時刻付きの区間（分かる場合）
+	Backend  string        // 使用したバックエンド
+	Elapsed  time.Duration // 処理時間
+}
+
+// TextSegmentは音声の先頭からの時刻が分かっているテキスト
+type TextSegment struct {
+	Start  time.Duration // 開始時刻
+	End    time.Duration // 終了時刻
+	Text   string        // テキスト
+	Tokens []Token       // トークンと確率（分かる場合）
+}
+
+// Configは文字起こしのバックエンドの設定
+type Config struct {
+	Backend   string          // 使用するバックエンド
+	Language  string          // 言語（空の場合は自動検出）
+	Whisper   WhisperSettings // whisper-cliとモデルの指定（BackendWhisperCLI・BackendScript）
+	Script    string          // BackendScriptで実行するスクリプト
+	ServerURL string          // BackendServerのURL
+	FakeText  string          // BackendFakeが返すテキスト（空の場合はファイル名と長さ）
+}
+
+// NewTranscriberは設定からバックエンドを選んで作成する
+func NewTranscriber(config Config) (Transcriber, error) {
+	language := config.Language
+
+	switch config.Backend {
+	case BackendWhisperCLI, "":
+		return NewWhisperCLI(config.Whisper, language), nil
+	case BackendScript:
+		if config.Script == "" {
+			return nil, fmt.Errorf("文字起こしスクリプトが指定されていません")
+		}
+		// 探索したwhisper-cliとモデルを環境変数でスクリプトに渡す
+		script := NewScriptTranscriber(config.Script, language)
+		install := DiscoverWhisper(config.Whisper)
+		if install.Binary != "" {
+			script.Env = append(script.Env, EnvWhisperCLI+"="+install.Binary)
+		}
+		if install.Model != "" {
+			script.Env = append(script.Env, EnvWhisperModel+"="+install.Model)
+		}
+		return script, nil
+	case BackendServer:
+		if config.ServerURL == "" {
+			return nil, fmt.Errorf("whisper.cppサーバーのURLが指定されていません")
+		}
+		return NewServerTranscriber(config.ServerURL, language), nil
+	case BackendFake:
+		return NewFakeTranscriber(config.FakeText), nil
+	}
+	return nil, fmt.Errorf("不明な文字起こしバックエンドです: %s（%s / %s / %s / %s）",
+		config.Backend, BackendWhisperCLI, BackendScript, BackendServer, BackendFake)
+}
+
+// 文字起こしする音声ファイルの絶対パス
+// サンプルが渡された場合は一時的なWAVファイルに書き出し、cleanupで削除する
+func (r Request) file() (path string, cleanup func(), err error) {
+	if len(r.Samples) == 0 {
+		path, err := filepath.Abs(r.Path)
+		if err != nil {
+			return "", nil, fmt.Errorf("絶対パスの取得に失敗: %v", err)
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return "", nil, fmt.Errorf("オーディオファイルが見つかりません: %s", path)
+		}
+		return path, func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "whisper_samples_")
+	if err != nil {
+		return "", nil, fmt.Errorf("一時ディレクトリを作成できませんでした: %v", err)
+	}
+	path = filepath.Join(dir, "samples.wav")
+	if err := audio.SaveAsWav(path, [][]float32{r.Samples}, r.SampleRate); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+	return path, func() { os.RemoveAll(dir) }, nil
+}
+
+// 音声の長さ
+func (r Request) duration() (time.Duration, error) {
+	if len(r.Samples) > 0 {
+		if r.SampleRate <= 0 {
+			return 0, fmt.Errorf("サンプリングレートが指定されていません")
+		}
+		return time.Duration(len(r.Samples)) * time.Second / time.Duration(r.SampleRate), nil
+	}
+	format, err := audio.ReadAudioInfo(r.Path)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(format.Frames) * time.Second / time.Duration(format.SampleRate), nil
+}
+
+// 音声の名前（表示用）
+func (r Request) name() string {
+	if len(r.Samples) > 0 {
+		return "サンプル"
+	}
+	return filepath.Base(r.Path)
+}
+
+// 言語の指定がない場合はdefaultLanguage、それもない場合は自動検出（auto）
+func (r Request) language(defaultLanguage string) string {
+	if r.Language != "" {
+		return r.Language
+	}
+	if defaultLanguage != "" {
+		return defaultLanguage
+	}
+	return LanguageAuto
+}
+
+// 結果に記録する言語（自動検出の指定は言語が分からないものとして空にする）
+func knownLanguage(language string) string {
+	if language == LanguageAuto {
+		return ""
+	}
+	return language
+}
+
+// FakeTranscriberは音声を解析せず、ファイル名と長さから決まった結果を返す
+// whisper.cppがない環境での動作確認に使う
+type FakeTranscriber struct {
+	Text string // 返すテキスト（空の場合はファイル名と長さ）
+}
+
+// 新しいフェイクのバックエンドを作成
+func NewFakeTranscriber(text string) *FakeTranscriber {
+	return &FakeTranscriber{Text: text}
+}
+
+func (t *FakeTranscriber) Name() string {
+	return BackendFake
+}
+
+func (t *FakeTranscriber) Check(ctx context.Context) error {
+	return nil
+}
+
+func (t *FakeTranscriber) Transcribe(ctx context.Context, request Request) (Result, error) {
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	duration, err := request.duration()
+	if err != nil {
+		return Result{}, err
+	}
+
+	text := t.Text
+	if text == "" {
+		text = fmt.Sprintf("%s の文字起こし（%.1f 秒）", request.name(), duration.Seconds())
+	}
+	text = strings.TrimSpace(text)
+	return Result{
+		Text:     text,
+		Language: request.language("ja"),
+		Segments: []TextSegment{{Start: 0, End: duration, Text: text}},
+		Backend:  t.Name(),
+	}, nil
+}
@@ -0,0 +1,100 @@
+package transcription
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"whisper_local_faster_whsiper_go/internal/app"
+)
+
+// 信頼度がこれ未満の区間をマークダウンで目立たせる
+const lowConfidence = 0.5
+
+// Transcriptは1つのセグメントの文字起こし結果
+type Transcript struct {
+	Text     string              // 話者名を付けて結合したテキスト（分析に渡す）
+	Channels []ChannelTranscript // チャンネルごとの結果
+}
+
+// ChannelTranscriptはチャンネルごとの文字起こし結果
+type ChannelTranscript struct {
+	Speaker string // 話者名（モノラルの場合は空）
+	Result  Result // 文字起こしの結果
+}
+
+// TimedTextは話者付きの時刻が分かっている区間
+type TimedText struct {
+	Speaker string // 話者名（モノラルの場合は空）
+	TextSegment
+}
+
+// Linesはすべてのチャンネルの区間を開始時刻の順に返す
+func (t Transcript) Lines() []TimedText {
+	var lines []TimedText
+	for _, channel := range t.Channels {
+		for _, segment := range channel.Result.Segments {
+			lines = append(lines, TimedText{Speaker: channel.Speaker, TextSegment: segment})
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Start < lines[j].Start })
+	return lines
+}
+
+// Languagesは検出した言語（重複を除く）
+func (t Transcript) Languages() []string {
+	var languages []string
+	for _, channel := range t.Channels {
+		language := channel.Result.Language
+		if language != "" && !slices.Contains(languages, language) {
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+// Confidenceはすべてのトークンの確率の平均（トークンがない場合はfalse）
+func (t Transcript) Confidence() (float64, bool) {
+	sum, count := 0.0, 0
+	for _, line := range t.Lines() {
+		for _, token := range line.Tokens {
+			sum += token.Probability
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return sum / float64(count), true
+}
+
+// LowConfidenceは信頼度が低い区間の数
+func (t Transcript) LowConfidence() int {
+	count := 0
+	for _, line := range t.Lines() {
+		if confidence, ok := line.Confidence(); ok && confidence < lowConfidence {
+			count++
+		}
+	}
+	return count
+}
+
+// Timelineは区間をセッション内の時刻付きで並べたマークダウンのリスト
+// startはセグメントの開始位置（セッション開始からの経過時刻）
+func (t Transcript) Timeline(start time.Duration) string {
+	var b strings.Builder
+	for _, line := range t.Lines() {
+		b.WriteString(fmt.Sprintf("- %s–%s ", app.FormatOffset(start+line.Start), app.FormatOffset(start+line.End)))
+		if line.Speaker != "" {
+			b.WriteString(fmt.Sprintf("[%s] ", line.Speaker))
+		}
+		b.WriteString(line.Text)
+		if confidence, ok := line.Confidence(); ok && confidence < lowConfidence {
+			b.WriteString(fmt.Sprintf(" ⚠ 信頼度 %.0f%%", confidence*100))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -1,153 +1,100 @@
 package transcription
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"time"
 )
 
-// WhisperPath定数
-const (
-	WhisperPath = "/Users/takeshiiijima/github/whisper.cpp/build/bin/whisper-cli"
-	ModelPath = "/Users/takeshiiijima/github/whisper.cpp/models/ggml-base.bin"
-)
-
-// 正規表現パターン
-var outputTxtRegex = regexp.MustCompile(`output_txt: saving output to ['"](.*?)['"]`)
-
-// CheckWhisperAvailabilityはWhisper.cppが使用可能か確認する
-func CheckWhisperAvailability() error {
-	// whisper-cliが存在するか確認
-	if _, err := os.Stat(WhisperPath); os.IsNotExist(err) {
-		return fmt.Errorf("whisper-cliが見つかりません: %s", WhisperPath)
-	}
-
-	// モデルファイルが存在するか確認
-	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
-		return fmt.Errorf("モデルファイルが見つかりません: %s", ModelPath)
-	}
+// WhisperCLIはwhisper.cppのwhisper-cliを実行して文字起こしする
+type WhisperCLI struct {
+	Settings WhisperSettings // 実行ファイルとモデルの指定
+	Path     string          // whisper-cliのパス（探索した結果）
+	Model    string          // モデルファイルのパス（探索した結果）
+	Language string          // 言語（空の場合は自動検出）
+}
 
-	// 実行権限の確認
-	info, _ := os.Stat(WhisperPath)
-	if info.Mode()&0111 == 0 {
-		return fmt.Errorf("whisper-cliに実行権限がありません: %s", WhisperPath)
-	}
+// 新しいwhisper-cliのバックエンドを作成（実行ファイルとモデルはsettingsをもとに探す）
+func NewWhisperCLI(settings WhisperSettings, language string) *WhisperCLI {
+	install := DiscoverWhisper(settings)
+	return &WhisperCLI{Settings: settings, Path: install.Binary, Model: install.Model, Language: language}
+}
 
-	return nil
+func (t *WhisperCLI) Name() string {
+	return BackendWhisperCLI
 }
 
-// 最新のテキストファイルを見つける
-func findLatestTxtFile(dirPath string) string {
-	files, err := os.ReadDir(dirPath)
+func (t *WhisperCLI) Check(ctx context.Context) error {
+	install, err := CheckWhisperAvailability(t.Settings)
 	if err != nil {
-		return ""
+		return err
 	}
-
-	var latestFile string
-	var latestTime time.Time
-
-	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(file.Name(), ".txt") {
-			filePath := filepath.Join(dirPath, file.Name())
-			fileInfo, err := file.Info()
-			if err != nil {
-				continue
-			}
-
-			// ファイルの更新時間を取得
-			modTime := fileInfo.ModTime()
-
-			// 最新のファイルを追跡
-			if latestFile == "" || modTime.After(latestTime) {
-				latestFile = filePath
-				latestTime = modTime
-			}
-		}
-	}
-
-	if latestFile != "" {
-		fmt.Printf("  最新のテキストファイルを使用: %s (%s)\n", 
-			filepath.Base(latestFile), latestTime.Format("2006-01-02 15:04:05"))
-	}
-
-	return latestFile
+	t.Path, t.Model = install.Binary, install.Model
+	return nil
 }
 
-// WhisperTranscribe は音声ファイルをWhisper.cppを使用して文字起こしします
-func WhisperTranscribe(audioPath string) (string, error) {
-	// 絶対パスに変換
-	audioAbsPath, err := filepath.Abs(audioPath)
+// Transcribe は音声ファイルをWhisper.cppを使用して文字起こしします
+func (t *WhisperCLI) Transcribe(ctx context.Context, request Request) (Result, error) {
+	if t.Path == "" || t.Model == "" {
+		return Result{}, fmt.Errorf("whisper-cliまたはモデルが見つかりません（-whisper-cli / -whisper-model で指定してください）")
+	}
+	audioAbsPath, cleanup, err := request.file()
 	if err != nil {
-		return "", fmt.Errorf("絶対パスの取得に失敗: %v", err)
+		return Result{}, err
 	}
+	defer cleanup()
 
-	// オーディオファイルの存在チェック
-	if _, err := os.Stat(audioAbsPath); os.IsNotExist(err) {
-		return "", fmt.Errorf("オーディオファイルが見つかりません: %s", audioAbsPath)
+	// 出力先を一時ディレクトリに指定し、他のセグメントの結果を読まないようにする
+	outDir, err := os.MkdirTemp("", "whisper_output_")
+	if err != nil {
+		return Result{}, fmt.Errorf("一時ディレクトリを作成できませんでした: %v", err)
 	}
+	defer os.RemoveAll(outDir)
+	outBase := filepath.Join(outDir, "transcript")
 
-	fmt.Printf("  文字起こし中: %s...\n", filepath.Base(audioAbsPath))
+	fmt.Printf("  文字起こし中: %s...\n", request.name())
+	started := time.Now()
 
-	// whisper.cppを実行するコマンドを構築
-	cmd := exec.Command(
-		WhisperPath,
-		"-m", ModelPath,
+	// whisper.cppを実行するコマンドを構築（トークンの確率を含むJSONを出力）
+	cmd := exec.CommandContext(ctx,
+		t.Path,
+		"-m", t.Model,
 		"-f", audioAbsPath,
-		"-l", "ja",
-		"--output-txt",
+		"-l", request.language(t.Language), // 指定がない場合は auto で自動検出
+		"--output-json-full",
+		"--output-file", outBase,
 		"--no-gpu",
 	)
 
 	// コマンドを実行
-	var output []byte
-	var cmdErr error
-	output, cmdErr = cmd.CombinedOutput()
-	if cmdErr != nil {
-		return "", fmt.Errorf("文字起こし実行エラー: %v", cmdErr)
-	}
-	
-	// コマンドは成功したが、デバッグのために出力を保存
-	debugOutput := string(output)
-	
-	// 出力されたテキストファイルを読み込み
-	// まず、標準のパスをチェック
-	txtPath := audioAbsPath + ".txt"
-	
-	// デバッグ出力からファイルパスを探す
-	var parsedPath string
-	if matched := outputTxtRegex.FindStringSubmatch(debugOutput); len(matched) > 1 {
-		parsedPath = matched[1]
-		fmt.Printf("  出力からファイルパスを探索: %s\n", parsedPath)
-		// ファイルが存在するか確認
-		if _, err := os.Stat(parsedPath); err == nil {
-			txtPath = parsedPath
-		}
-	}
-	
-	// ファイルがディレクトリに存在するか確認
-	if _, err := os.Stat(txtPath); os.IsNotExist(err) {
-		// 最新の.txtファイルを探す
-		txtPath = findLatestTxtFile(filepath.Dir(audioAbsPath))
-		if txtPath == "" {
-			fmt.Printf("  文字起こしファイルが見つかりません\n")
-			// デバッグ情報を表示
-			fmt.Printf("  コマンド出力: %s\n", debugOutput)
-			return "", fmt.Errorf("文字起こし結果が見つかりません")
-		}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return Result{}, fmt.Errorf("文字起こし実行エラー: %v\n出力: %s", err, strings.TrimSpace(string(output)))
 	}
 
-	// テキストファイルの内容を読み込む
-	content, err := os.ReadFile(txtPath)
+	// 出力されたJSONを読み込む
+	data, err := os.ReadFile(outBase + ".json")
 	if err != nil {
-		return "", fmt.Errorf("文字起こしファイル読み込みエラー: %v", err)
+		fmt.Printf("  コマンド出力: %s\n", string(output))
+		return Result{}, fmt.Errorf("文字起こし結果が見つかりません: %v", err)
+	}
+	result, err := ParseWhisperJSON(data)
+	if err != nil {
+		return Result{}, err
+	}
+	if result.Language == "" {
+		result.Language = knownLanguage(request.language(t.Language))
 	}
+	result.Backend = t.Name()
+	result.Elapsed = time.Since(started)
 
 	// 成功メッセージ
-	fmt.Printf("  文字起こし完了: %s (%d文字)\n", filepath.Base(txtPath), len(content))
+	fmt.Printf("  文字起こし完了: %s (%d文字、%d区間、言語: %s)\n",
+		request.name(), len(result.Text), len(result.Segments), result.Language)
 
-	return strings.TrimSpace(string(content)), nil
+	return result, nil
 }
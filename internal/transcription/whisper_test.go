@@ -0,0 +1,115 @@
+package transcription
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// whisper-cliの --output-json-full の出力（一部）
+const whisperFullJSON = `{
+  "params": {"model": "models/ggml-base.bin", "language": "auto", "translate": false},
+  "result": {"language": "ja"},
+  "transcription": [
+    {"timestamps": {"from": "00:00:00,000", "to": "00:00:02,000"},
+     "offsets": {"from": 0, "to": 2000},
+     "text": " こんにちは",
+     "tokens": [
+       {"text": "[_BEG_]", "offsets": {"from": 0, "to": 0}, "id": 50364, "p": 0.99},
+       {"text": " こんにちは", "offsets": {"from": 0, "to": 2000}, "id": 35580, "p": 0.25},
+       {"text": "[_TT_100]", "offsets": {"from": 2000, "to": 2000}, "id": 50464, "p": 0.5}
+     ]},
+    {"offsets": {"from": 2000, "to": 2500}, "text": " ", "tokens": []}
+  ]
+}`
+
+func TestParseWhisperJSON(t *testing.T) {
+	result, err := ParseWhisperJSON([]byte(whisperFullJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Language != "ja" || result.Text != "こんにちは" {
+		t.Errorf("結果 = %q（言語: %q）", result.Text, result.Language)
+	}
+	// 空の区間と特殊トークンは除く
+	if len(result.Segments) != 1 || len(result.Segments[0].Tokens) != 1 {
+		t.Fatalf("区間 = %+v", result.Segments)
+	}
+	token := result.Segments[0].Tokens[0]
+	if token.ID != 35580 || token.Probability != 0.25 || token.End != 2*time.Second {
+		t.Errorf("トークン = %+v", token)
+	}
+
+	// 言語を検出できなかった場合、auto の指定は言語として扱わない
+	result, err = ParseWhisperJSON([]byte(`{"params": {"language": "auto"}, "transcription": []}`))
+	if err != nil || result.Language != "" {
+		t.Errorf("言語 = %q（%v）", result.Language, err)
+	}
+	if _, err := ParseWhisperJSON([]byte("not json")); err == nil {
+		t.Error("不正なJSONでエラーが返りません")
+	}
+}
+
+// 引数を記録して決まったJSONを出力するwhisper-cliの代わり
+func fakeWhisperCLI(t *testing.T) (binary, model, argsFile string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("シェルスクリプトを実行できません")
+	}
+	dir := t.TempDir()
+	argsFile = filepath.Join(dir, "args.txt")
+	jsonFile := filepath.Join(dir, "output.json")
+	if err := os.WriteFile(jsonFile, []byte(whisperFullJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	binary = filepath.Join(dir, "whisper-cli")
+	script := `#!/bin/sh
+echo "$@" > "` + argsFile + `"
+while [ $# -gt 0 ]; do
+  if [ "$1" = "--output-file" ]; then cp "` + jsonFile + `" "$2.json"; fi
+  shift
+done
+`
+	if err := os.WriteFile(binary, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	model = filepath.Join(dir, "ggml-base.bin")
+	if err := os.WriteFile(model, []byte("model"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return binary, model, argsFile
+}
+
+func TestWhisperCLILanguage(t *testing.T) {
+	binary, model, argsFile := fakeWhisperCLI(t)
+	audioPath := writeTestWav(t, 2)
+
+	tests := []struct {
+		configured string // -language の指定
+		request    string // Request.Language
+		want       string // whisper-cliに渡す -l
+	}{
+		{"", "", "auto"},
+		{"ja", "", "ja"},
+		{"ja", "en", "en"},
+	}
+	for _, tt := range tests {
+		transcriber := &WhisperCLI{Path: binary, Model: model, Language: tt.configured}
+		result, err := transcriber.Transcribe(context.Background(), Request{Path: audioPath, Language: tt.request})
+		if err != nil {
+			t.Fatal(err)
+		}
+		args, _ := os.ReadFile(argsFile)
+		if !strings.Contains(string(args), "-l "+tt.want+" ") {
+			t.Errorf("設定 %q・要求 %q: 引数 = %s（期待値 -l %s）", tt.configured, tt.request, args, tt.want)
+		}
+		if result.Language != "ja" || len(result.Segments) != 1 || result.Backend != BackendWhisperCLI {
+			t.Errorf("結果 = %+v", result)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package transcription
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tokenはwhisperが出力したトークンと確率
+type Token struct {
+	ID          int           // トークンID
+	Text        string        // テキスト（単語の途中で切れている場合がある）
+	Probability float64       // 確率（0〜1）
+	Start       time.Duration // 開始時刻
+	End         time.Duration // 終了時刻
+}
+
+// Confidenceはトークンの確率の平均（トークンがない場合はfalse）
+func (s TextSegment) Confidence() (float64, bool) {
+	if len(s.Tokens) == 0 {
+		return 0, false
+	}
+	sum := 0.0
+	for _, token := range s.Tokens {
+		sum += token.Probability
+	}
+	return sum / float64(len(s.Tokens)), true
+}
+
+// whisper-cliの --output-json-full の形式
+type whisperJSON struct {
+	Params struct {
+		Language string `json:"language"`
+	} `json:"params"`
+	Result struct {
+		Language string `json:"language"`
+	} `json:"result"`
+	Transcription []struct {
+		Offsets whisperOffsets `json:"offsets"`
+		Text    string         `json:"text"`
+		Tokens  []struct {
+			ID      int            `json:"id"`
+			Text    string         `json:"text"`
+			P       float64        `json:"p"`
+			Offsets whisperOffsets `json:"offsets"`
+		} `json:"tokens"`
+	} `json:"transcription"`
+}
+
+// 音声の先頭からの位置（ミリ秒）
+type whisperOffsets struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+// ParseWhisperJSONはwhisper-cliのJSON出力（--output-json / --output-json-full）を解釈する
+// 区間ごとの時刻・テキスト・トークンの確率と、検出した言語を返す
+func ParseWhisperJSON(data []byte) (Result, error) {
+	var output whisperJSON
+	if err := json.Unmarshal(data, &output); err != nil {
+		return Result{}, fmt.Errorf("whisperのJSON出力を解釈できませんでした: %v", err)
+	}
+
+	result := Result{Language: output.Result.Language}
+	if result.Language == "" {
+		result.Language = knownLanguage(output.Params.Language)
+	}
+
+	texts := make([]string, 0, len(output.Transcription))
+	for _, item := range output.Transcription {
+		segment := TextSegment{
+			Start: time.Duration(item.Offsets.From) * time.Millisecond,
+			End:   time.Duration(item.Offsets.To) * time.Millisecond,
+			Text:  strings.TrimSpace(item.Text),
+		}
+		for _, token := range item.Tokens {
+			// [_BEG_] や [_TT_150] などの特殊トークンは除く
+			if isSpecialToken(token.Text) {
+				continue
+			}
+			segment.Tokens = append(segment.Tokens, Token{
+				ID:          token.ID,
+				Text:        token.Text,
+				Probability: token.P,
+				Start:       time.Duration(token.Offsets.From) * time.Millisecond,
+				End:         time.Duration(token.Offsets.To) * time.Millisecond,
+			})
+		}
+		if segment.Text == "" {
+			continue
+		}
+		result.Segments = append(result.Segments, segment)
+		texts = append(texts, segment.Text)
+	}
+	result.Text = strings.Join(texts, "\n")
+	return result, nil
+}
+
+// whisperの特殊トークン（[_BEG_]・[_TT_150]・<|endoftext|> など）
+func isSpecialToken(text string) bool {
+	return (strings.HasPrefix(text, "[_") && strings.HasSuffix(text, "]")) ||
+		(strings.HasPrefix(text, "<|") && strings.HasSuffix(text, "|>"))
+}
@@ -1,37 +1,390 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
-
-	"github.com/gordonklaus/portaudio"
+	"time"
 
 	"whisper_local_faster_whsiper_go/internal/app"
+	"whisper_local_faster_whsiper_go/internal/audio"
+	"whisper_local_faster_whsiper_go/internal/audio/device"
 	"whisper_local_faster_whsiper_go/internal/transcription"
 )
 
 func main() {
-	// PortAudioを初期化
-	if err := portaudio.Initialize(); err != nil {
-		fmt.Printf("PortAudio初期化エラー: %v\n", err)
-		os.Exit(1)
+	// サブコマンドの判定
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "transcribe":
+			runTranscribe(os.Args[2:])
+			return
+		case "cleanup":
+			runCleanup(os.Args[2:])
+			return
+		case "stitch":
+			runStitch(os.Args[2:])
+			return
+		}
 	}
-	defer portaudio.Terminate()
+
+	runRecord(os.Args[1:])
+}
+
+// 録音して文字起こし（デフォルトのコマンド）
+func runRecord(args []string) {
+	// 入力源の指定（未指定の場合はマイクから録音）
+	flags := flag.NewFlagSet("record", flag.ExitOnError)
+	inputFile := flags.String("input", "", "マイクの代わりに再生するWAV・FLACファイル")
+	toneFreq := flags.Float64("tone", 0, "マイクの代わりに生成する正弦波の周波数（Hz）")
+	toneDuration := flags.Duration("duration", time.Minute, "正弦波を生成する長さ")
+	realtime := flags.Bool("realtime", false, "ファイル・正弦波を実時間の速度で読み出す")
+	fixedSegments := flags.Bool("fixed-segments", false, "発話の切れ目ではなく録音間隔ごとに区切る")
+	minSegment := flags.Duration("min-segment", 0, "セグメントの最小長（発話区切り時）")
+	maxSegment := flags.Duration("max-segment", 0, "セグメントの最大長（発話区切り時）")
+	nativeRate := flags.Bool("native-rate", false, "16kHzに変換せず録音時のサンプリングレートで保存する")
+	numChannels := flags.Int("channels", 0, "録音するチャンネル数（0の場合はデバイスの最大数）")
+	channelMode := flags.String("channel-mode", app.ChannelModeMix, "複数チャンネルの扱い: mix（混合）/ interleaved（多チャンネルWAV）/ split（チャンネル別WAV）")
+	fallbackDevices := flags.String("fallback-device", "", "入力デバイスが失われた場合に使う代替デバイス名（カンマ区切り）")
+	speakers := flags.String("speakers", "", "チャンネルごとの話者名（カンマ区切り）")
+	keepOriginal := flags.Bool("keep-original", false, "元のサンプリングレートの音声を recordings/original に保管する")
+	archiveFlac := flags.Bool("archive-flac", false, "文字起こしが終わったセグメントをFLACに変換して保管する（WAVは削除）")
+	sampleFormat := flags.String("sample-format", "16", "保存するWAVのサンプル形式: 16（16bit PCM）/ 24（24bit PCM）/ float（32bit 浮動小数点）")
+	dither := flags.Bool("dither", false, "整数PCMに変換する際にTPDFディザーを加える")
+	silenceThreshold := flags.Float64("silence-threshold", 0.01, "無音とみなすRMSの閾値（0で無音判定を無効化）")
+	silenceAction := flags.String("silence-action", app.SilenceActionNote, "無音セグメントの扱い: drop（破棄）/ note（マークダウンに無音と記録）")
+	retention := retentionFlags(flags)
+	transcriber := transcriberFlags(flags)
+	flags.Parse(args)
 
 	// アプリケーションインスタンスを作成
+	myApp := setupApp(transcriber())
+
+	// セグメントの区切り方を設定
+	myApp.UseVAD = !*fixedSegments
+	if *minSegment > 0 {
+		myApp.VAD.MinSegment = *minSegment
+	}
+	if *maxSegment > 0 {
+		myApp.VAD.MaxSegment = *maxSegment
+	}
+	if myApp.VAD.MinSegment > myApp.VAD.MaxSegment {
+		fmt.Printf("\nエラー: セグメントの最小長が最大長を超えています\n")
+		os.Exit(1)
+	}
+
+	// 保存形式を設定
+	myApp.WhisperReady = !*nativeRate
+	myApp.KeepOriginal = *keepOriginal
+	myApp.ArchiveFlac = *archiveFlac
+	format, err := audio.ParseSampleFormat(*sampleFormat)
+	if err != nil {
+		fmt.Printf("\nエラー: %v\n", err)
+		os.Exit(1)
+	}
+	myApp.WavOptions = audio.WavOptions{Format: format, Dither: *dither}
+
+	// 保存ルールを設定（文字起こし済みの削除は処理ごと、それ以外は録音終了時に適用）
+	policy, err := retention()
+	if err != nil {
+		fmt.Printf("\nエラー: %v\n", err)
+		os.Exit(1)
+	}
+	myApp.Retention = policy
+
+	// 入力デバイスが失われた場合の代替デバイス
+	if *fallbackDevices != "" {
+		for _, name := range strings.Split(*fallbackDevices, ",") {
+			myApp.FallbackDevices = append(myApp.FallbackDevices, strings.TrimSpace(name))
+		}
+	}
+
+	// 無音セグメントの扱いを設定
+	myApp.SilenceThreshold = *silenceThreshold
+	switch *silenceAction {
+	case app.SilenceActionDrop, app.SilenceActionNote:
+		myApp.SilenceAction = *silenceAction
+	default:
+		fmt.Printf("\nエラー: 不明な無音セグメントの扱いです: %s\n", *silenceAction)
+		os.Exit(1)
+	}
+
+	// チャンネルの扱いを設定
+	switch *channelMode {
+	case app.ChannelModeMix, app.ChannelModeInterleaved, app.ChannelModeSplit:
+		myApp.ChannelMode = *channelMode
+	default:
+		fmt.Printf("\nエラー: 不明なチャンネルモードです: %s\n", *channelMode)
+		os.Exit(1)
+	}
+	if *speakers != "" {
+		for _, name := range strings.Split(*speakers, ",") {
+			myApp.ChannelNames = append(myApp.ChannelNames, strings.TrimSpace(name))
+		}
+	}
+
+	// 入力源を決定
+	var source audio.AudioSource
+	switch {
+	case *inputFile != "":
+		source = audio.NewFileSource(*inputFile, *realtime)
+		myApp.DeviceName = "ファイル: " + *inputFile
+	case *toneFreq > 0:
+		source = audio.NewToneSource(*toneFreq, *toneDuration, app.SampleRate, *realtime)
+		myApp.DeviceName = fmt.Sprintf("正弦波: %.0f Hz", *toneFreq)
+	default:
+		// PortAudioはデバイスから録音する場合のみ初期化する
+		if err := device.Initialize(); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		defer device.Terminate()
+		source = selectDeviceSource(myApp, *numChannels)
+	}
+
+	// 前回強制終了した録音があれば復旧して処理待ちに追加
+	if count := myApp.RecoverRecordings(); count > 0 {
+		fmt.Printf("\n%d 件の中断された録音を文字起こしします\n", count)
+	}
+
+	// マークダウンファイルを初期化
+	myApp.InitializeMarkdownFile()
+
+	// コンテキスト作成
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// シグナルハンドラの設定
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	// 処理ワーカーの開始（録音の終了後に残りを処理して終了する）
+	recordingDone := make(chan struct{})
+	myApp.StartProcessingWorker(recordingDone)
+
+	// 別のgoroutineでシグナルを待機
+	go func() {
+		<-sigChan
+		fmt.Println("\n録音を停止中...")
+		cancel() // コンテキストをキャンセル
+	}()
+
+	// キー入力とシグナルによる一時停止・再開
+	watchRecordingControls(myApp)
+
+	// 録音開始
+	if err := myApp.RecordFromSource(ctx, source); err != nil {
+		fmt.Printf("録音エラー: %v\n", err)
+	}
+
+	// 録音終了の処理
+	myApp.SaveAudioSegment() // 残りのバッファを保存
+	// 最後のセグメントを処理待ちに追加した後でワーカーに録音の終了を知らせる
+	close(recordingDone)
+	fmt.Println("処理中のファイルを完了中...")
+	myApp.WaitForCompletion()
+	myApp.AddRecordingEndNote()
+	if myApp.Retention.Enabled() {
+		printCleanupResult(myApp.ApplyRetention(myApp.Retention))
+	}
+	fmt.Println("録音を終了しました")
+}
+
+// 既存のWAVファイルを文字起こし（PortAudioは使用しない）
+func runTranscribe(args []string) {
+	flags := flag.NewFlagSet("transcribe", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "使用方法: %s transcribe <WAV・FLACファイルまたはディレクトリ>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	transcriber := transcriberFlags(flags)
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	myApp := setupApp(transcriber())
+	myApp.DeviceName = "ファイル取り込み"
+
+	count, err := myApp.QueueFiles(flags.Args())
+	if err != nil {
+		fmt.Printf("\nエラー: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\n%d 件のファイルを処理します\n", count)
+
+	myApp.InitializeMarkdownFile()
+	myApp.ProcessPendingFiles()
+	myApp.AddRecordingEndNote()
+	fmt.Println("文字起こしを終了しました")
+}
+
+// セッションのセグメントを1つの録音ファイルにつなげる（PortAudio・whisper・Ollamaは使用しない）
+func runStitch(args []string) {
+	flags := flag.NewFlagSet("stitch", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "使用方法: %s stitch [-session ID] [-o 出力.wav|出力.flac] [WAV・FLACファイルまたはディレクトリ]...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	sessionID := flags.String("session", "", "つなげるセッションID（複数のセッションが含まれる場合に指定）")
+	output := flags.String("o", "", "出力ファイル（拡張子が .flac の場合はFLAC。未指定の場合は session_<ID>.wav）")
+	sampleFormat := flags.String("sample-format", "16", "出力のサンプル形式: 16 / 24 / float（floatはWAVのみ）")
+	flags.Parse(args)
+
+	format, err := audio.ParseSampleFormat(*sampleFormat)
+	if err != nil {
+		fmt.Printf("\nエラー: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 入力が未指定の場合は録音ディレクトリのセグメントを使う
+	inputs := flags.Args()
+	if len(inputs) == 0 {
+		inputs = []string{app.NewApp().RecordingDir}
+	}
+
+	result, err := app.StitchSession(inputs, app.StitchOptions{
+		SessionID: *sessionID,
+		Output:    *output,
+		Format:    audio.WavOptions{Format: format},
+	})
+	for _, skipped := range result.Skipped {
+		fmt.Printf("メタデータがないため除外: %s\n", skipped)
+	}
+	if err != nil {
+		fmt.Printf("\nエラー: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\nセッション %s の %d セグメントをつなげました: %s\n", result.SessionID, result.Segments, result.Output)
+	fmt.Printf("長さ: %s（録音なしの区間 %d 件、計 %s を無音で補完）\n",
+		app.FormatOffset(result.Duration), result.Gaps, app.FormatOffset(result.GapDuration))
+	fmt.Printf("マーカー: %d 件\n", result.Markers)
+	if result.Clipped > 0 {
+		fmt.Printf("クリップしたサンプル数: %d\n", result.Clipped)
+	}
+}
+
+// 保存ルールに従って古い録音と文字起こし結果を削除（PortAudio・whisper・Ollamaは使用しない）
+func runCleanup(args []string) {
+	flags := flag.NewFlagSet("cleanup", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "使用方法: %s cleanup [-max-size 2GB] [-max-age 720h] [-delete-transcribed]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	retention := retentionFlags(flags)
+	flags.Parse(args)
+
+	policy, err := retention()
+	if err != nil {
+		fmt.Printf("\nエラー: %v\n", err)
+		os.Exit(1)
+	}
+	if !policy.Enabled() {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	myApp := app.NewApp()
+	fmt.Printf("録音ディレクトリ: %s\n", myApp.RecordingDir)
+	fmt.Printf("文字起こしディレクトリ: %s\n", myApp.TranscriptsDir)
+	printCleanupResult(myApp.ApplyRetention(policy))
+}
+
+// 保存ルールのフラグを登録し、解析後に保存ルールを返す関数を返す
+func retentionFlags(flags *flag.FlagSet) func() (app.RetentionPolicy, error) {
+	maxSize := flags.String("max-size", "", "録音と文字起こし結果の合計サイズの上限（例: 2GB）。超えた分は古いものから削除")
+	maxAge := flags.Duration("max-age", 0, "これより古い録音と文字起こし結果を削除（例: 720h）")
+	deleteTranscribed := flags.Bool("delete-transcribed", false, "文字起こしが終わった録音を削除する")
+
+	return func() (app.RetentionPolicy, error) {
+		policy := app.RetentionPolicy{
+			MaxAge:            *maxAge,
+			DeleteTranscribed: *deleteTranscribed,
+		}
+		if *maxSize != "" {
+			size, err := app.ParseSize(*maxSize)
+			if err != nil {
+				return policy, err
+			}
+			policy.MaxTotalSize = size
+		}
+		return policy, nil
+	}
+}
+
+// 文字起こしのバックエンドのフラグを登録し、解析後に設定を返す関数を返す
+func transcriberFlags(flags *flag.FlagSet) func() transcription.Config {
+	backend := flags.String("transcriber", transcription.BackendWhisperCLI,
+		"文字起こしのバックエンド: whisper-cli / script（transcribe.sh）/ server（whisper.cppサーバー）/ fake（動作確認用）")
+	script := flags.String("transcribe-script", "", "-transcriber script で実行するスクリプト（未指定の場合は ./transcribe.sh）")
+	serverURL := flags.String("whisper-server", "http://127.0.0.1:8080", "-transcriber server で接続するwhisper.cppサーバーのURL")
+	language := flags.String("language", "", "文字起こしする言語（例: ja、en。未指定の場合は自動検出）")
+	whisperCLI := flags.String("whisper-cli", "", "whisper-cliのパス（未指定の場合は WHISPER_CLI、PATH、よくあるインストール先の順に探す）")
+	whisperModel := flags.String("whisper-model", "", "モデルファイルのパスまたは名前（例: base、small。未指定の場合は WHISPER_MODEL、ggml-base.bin の順）")
+	modelsDir := flags.String("whisper-models-dir", "", "モデル（ggml-*.bin）を探すディレクトリ（未指定の場合は WHISPER_MODELS_DIR）")
+
+	return func() transcription.Config {
+		return transcription.Config{
+			Backend:   *backend,
+			Language:  *language,
+			Script:    *script,
+			ServerURL: *serverURL,
+			Whisper: transcription.WhisperSettings{
+				Binary:    *whisperCLI,
+				Model:     *whisperModel,
+				ModelsDir: *modelsDir,
+			},
+		}
+	}
+}
+
+// 保存ルールの適用結果を表示
+func printCleanupResult(result app.CleanupResult, err error) {
+	if err != nil {
+		fmt.Printf("%s\n", app.ErrorMessage("保存ルールの適用エラー: "+err.Error()))
+		return
+	}
+	fmt.Printf("%s\n", app.SuccessMessage(fmt.Sprintf("%d 件のファイルを削除しました（%s）。残り %s",
+		result.Deleted, app.FormatSize(result.FreedBytes), app.FormatSize(result.TotalBytes))))
+	if result.Kept > 0 {
+		fmt.Printf("%s\n", app.InfoMessage(fmt.Sprintf("処理待ち・文字起こし前のため %d 件のファイルを残しました", result.Kept)))
+	}
+}
+
+// アプリケーションを作成し、文字起こしのバックエンドとOllamaが使用可能か確認
+func setupApp(config transcription.Config) *app.App {
 	myApp := app.NewApp()
 
+	// 文字起こしのバックエンドを作成
+	if config.Script == "" {
+		config.Script = myApp.TranscribeScript
+	}
+	transcriber, err := transcription.NewTranscriber(config)
+	if err != nil {
+		fmt.Printf("\nエラー: %v\n", err)
+		os.Exit(1)
+	}
+	myApp.TranscribeScript = config.Script
+	myApp.TranscriberName = transcriber.Name()
+
 	// ProcessAudio関数を設定
-	myApp.SetProcessAudioFunc(transcription.ProcessAudio)
+	myApp.SetProcessAudioFunc(func(a *app.App, segment app.Segment) {
+		transcription.ProcessAudio(context.Background(), transcriber, a, segment)
+	})
 
 	myApp.PrintSystemInfo()
 
-	// Whisper.cppが使用可能か確認
-	if err := transcription.CheckWhisperAvailability(); err != nil {
+	// 文字起こしのバックエンドが使用可能か確認
+	if err := transcriber.Check(context.Background()); err != nil {
 		fmt.Printf("\nエラー: %v\n", err)
 		os.Exit(1)
 	}
@@ -45,17 +398,23 @@ func main() {
 	}
 
 	fmt.Printf("\nシステム確認完了:\n")
-	fmt.Printf("- 音声文字起こし: whisper.cppを直接使用\n")
+	fmt.Printf("- 音声文字起こし: %s\n", transcriber.Name())
 	fmt.Printf("- テキスト分析: Ollama\n")
 	fmt.Printf("- 使用モデル: %s\n", app.OllamaModel)
 	fmt.Printf("- 全てローカル環境で動作します（インターネット不要）\n")
 
+	return myApp
+}
+
+// マイクデバイスを選択して入力源を作成
+func selectDeviceSource(myApp *app.App, channels int) audio.AudioSource {
 	// 利用可能なデバイス一覧表示
-	devices, err := myApp.ListAudioDevices()
+	devices, err := device.InputDevices()
 	if err != nil {
 		fmt.Printf("オーディオデバイス一覧を取得できませんでした: %v\n", err)
 		os.Exit(1)
 	}
+	myApp.PrintAudioDevices(devices)
 
 	if len(devices) == 0 {
 		fmt.Println("利用可能な入力デバイスがありません")
@@ -79,38 +438,42 @@ func main() {
 	}
 
 	selectedDevice := devices[deviceIndex]
-	myApp.DeviceName = selectedDevice.Name
-
-	// マークダウンファイルを初期化
-	myApp.InitializeMarkdownFile()
-
-	// コンテキスト作成
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// シグナルハンドラの設定
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	if err := myApp.SelectDevice(selectedDevice, channels); err != nil {
+		fmt.Printf("デバイス設定エラー: %v\n", err)
+		os.Exit(1)
+	}
 
-	// 処理ワーカーの開始
-	go myApp.ProcessingWorker(ctx)
+	source := device.NewPortAudioSource(selectedDevice, myApp.Channels, myApp.SampleRate, myApp.SampleRate/4)
+	source.Fallbacks = myApp.FallbackDevices
+	return source
+}
 
-	// 別のgoroutineでシグナルを待機
+// 一時停止・再開とブックマークの操作を受け付ける
+// p + Enter で切り替え、SIGUSR1 で一時停止、SIGUSR2 で再開
+// b + Enter でブックマーク（b の後にラベルを続けて入力できる）
+func watchRecordingControls(myApp *app.App) {
+	ctlChan := make(chan os.Signal, 1)
+	signal.Notify(ctlChan, syscall.SIGUSR1, syscall.SIGUSR2)
 	go func() {
-		<-sigChan
-		fmt.Println("\n録音を停止中...")
-		cancel() // コンテキストをキャンセル
+		for sig := range ctlChan {
+			if sig == syscall.SIGUSR1 {
+				myApp.Pause()
+			} else {
+				myApp.Resume()
+			}
+		}
 	}()
 
-	// 録音開始
-	if err := myApp.StartRecording(ctx, &selectedDevice); err != nil {
-		fmt.Printf("録音エラー: %v\n", err)
-	}
-
-	// 録音終了の処理
-	myApp.SaveAudioSegment() // 残りのバッファを保存
-	fmt.Println("処理中のファイルを完了中...")
-	myApp.WaitForCompletion()
-	myApp.AddRecordingEndNote()
-	fmt.Println("録音を終了しました")
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			command, label, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+			switch command {
+			case "p":
+				myApp.TogglePause()
+			case "b":
+				myApp.AddBookmark(label)
+			}
+		}
+	}()
 }
@@ -1,5 +1,12 @@
 #!/bin/bash
 # 音声ファイルの文字起こしを行うシェルスクリプト
+#
+# whisper-cliとモデルは環境変数で指定できます（アプリから実行した場合は探索した結果が渡されます）
+#   WHISPER_CLI         whisper-cliのパス
+#   WHISPER_MODEL       モデルファイルのパスまたは名前（例: base、small）
+#   WHISPER_MODELS_DIR  モデルを探すディレクトリ
+#   WHISPER_CPP_DIR     whisper.cppのソースディレクトリ
+#   WHISPER_LANGUAGE    言語（デフォルト: auto で自動検出）
 
 # 入力チェック
 if [ "$#" -ne 1 ]; then
@@ -8,8 +15,9 @@ if [ "$#" -ne 1 ]; then
 fi
 
 INPUT_FILE="$1"
-WHISPER_PATH="/Users/takeshiiijima/github/whisper.cpp/build/bin/whisper-cli"
-MODEL_PATH="/Users/takeshiiijima/github/whisper.cpp/models/ggml-base.bin"
+WHISPER_PATH="${WHISPER_CLI:-}"
+MODEL_PATH="${WHISPER_MODEL:-}"
+LANGUAGE="${WHISPER_LANGUAGE:-auto}"
 
 # ファイルの存在チェック
 if [ ! -f "$INPUT_FILE" ]; then
@@ -17,20 +25,64 @@ if [ ! -f "$INPUT_FILE" ]; then
     exit 1
 fi
 
-if [ ! -f "$WHISPER_PATH" ]; then
-    echo "エラー: whisper-cliが見つかりません: $WHISPER_PATH"
-    exit 1
+# whisper-cliを探す（PATH → whisper.cppのビルドディレクトリ → よくあるインストール先）
+if [ -z "$WHISPER_PATH" ]; then
+    for name in whisper-cli whisper-cpp main; do
+        if command -v "$name" > /dev/null 2>&1; then
+            WHISPER_PATH="$(command -v "$name")"
+            break
+        fi
+    done
+fi
+if [ -z "$WHISPER_PATH" ]; then
+    for dir in "${WHISPER_CPP_DIR:-}" ./whisper.cpp ../whisper.cpp "$HOME/whisper.cpp" "$HOME/github/whisper.cpp" "$HOME/src/whisper.cpp" "$HOME/dev/whisper.cpp" /opt/whisper.cpp; do
+        if [ -n "$dir" ] && [ -x "$dir/build/bin/whisper-cli" ]; then
+            WHISPER_PATH="$dir/build/bin/whisper-cli"
+            break
+        fi
+    done
+fi
+if [ -z "$WHISPER_PATH" ]; then
+    for candidate in /opt/homebrew/bin/whisper-cli /usr/local/bin/whisper-cli "$HOME/.local/bin/whisper-cli"; do
+        if [ -x "$candidate" ]; then
+            WHISPER_PATH="$candidate"
+            break
+        fi
+    done
 fi
 
-if [ ! -f "$MODEL_PATH" ]; then
-    echo "エラー: モデルファイルが見つかりません: $MODEL_PATH"
+if [ -z "$WHISPER_PATH" ] || [ ! -x "$WHISPER_PATH" ]; then
+    echo "エラー: whisper-cliが見つかりません: ${WHISPER_PATH:-（PATHとwhisper.cppのビルドディレクトリを探しました）}"
+    echo "whisper.cppをインストールまたはビルドし、WHISPER_CLI で実行ファイルを指定してください"
     exit 1
 fi
 
+# モデルを探す（パスで指定されていない場合は名前としてモデルのディレクトリから探す）
+if [ -z "$MODEL_PATH" ] || [ ! -f "$MODEL_PATH" ]; then
+    MODEL_NAME="${MODEL_PATH:-base}"
+    MODEL_NAME="${MODEL_NAME#ggml-}"
+    MODEL_NAME="${MODEL_NAME%.bin}"
+    MODEL_PATH=""
+    BIN_DIR="$(dirname "$WHISPER_PATH")"
+    for dir in "${WHISPER_MODELS_DIR:-}" "$BIN_DIR/../../models" "$BIN_DIR/../share/whisper-cpp" "${WHISPER_CPP_DIR:-}/models" ./models "$HOME/whisper.cpp/models" "$HOME/github/whisper.cpp/models" "$HOME/src/whisper.cpp/models"; do
+        if [ -n "$dir" ] && [ -f "$dir/ggml-$MODEL_NAME.bin" ]; then
+            MODEL_PATH="$dir/ggml-$MODEL_NAME.bin"
+            break
+        fi
+    done
+    if [ -z "$MODEL_PATH" ]; then
+        echo "エラー: モデルファイルが見つかりません: ggml-$MODEL_NAME.bin"
+        echo "whisper.cppの models/download-ggml-model.sh $MODEL_NAME でダウンロードし、WHISPER_MODEL または WHISPER_MODELS_DIR で指定してください"
+        exit 1
+    fi
+fi
+
 echo "文字起こし開始: $INPUT_FILE"
+echo "whisper-cli: $WHISPER_PATH"
+echo "モデル: $MODEL_PATH"
 
 # whisper.cppを実行
-"$WHISPER_PATH" -m "$MODEL_PATH" -f "$INPUT_FILE" -l ja --output-txt --no-gpu
+"$WHISPER_PATH" -m "$MODEL_PATH" -f "$INPUT_FILE" -l "$LANGUAGE" --output-txt --no-gpu
 
 # 実行結果の確認
 if [ $? -eq 0 ]; then